@@ -0,0 +1,149 @@
+package springweb
+
+import "math"
+
+func (w *World) StableDuration() float64 {
+	rows := make([]float64, len(w.Nodes))
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		for _, s := range n.Springs {
+			to := &w.Nodes[s.To]
+			d := distance(n, to)
+			if d == 0 {
+				continue
+			}
+			k := s.K + (s.FromArm.K+s.ToArm.K)/(d*d)
+			if k <= 0 {
+				continue
+			}
+			coupling := math.Sqrt(n.inverseMass() * to.inverseMass())
+			rows[i] += k * (n.inverseMass() + coupling)
+			rows[s.To] += k * (to.inverseMass() + coupling)
+		}
+	}
+	maxRow := 0.
+	for _, row := range rows {
+		maxRow = math.Max(maxRow, row)
+	}
+	if maxRow == 0 {
+		return math.Inf(1)
+	}
+	return 2 / math.Sqrt(maxRow)
+}
+
+type Adaptive struct {
+	Integrator   Integrator
+	Tolerance    float64
+	MinDuration  float64
+	Substeps     int
+	Error        float64
+	hint         float64
+	x, y, vx, vy []float64
+	fullX, fullY []float64
+	capped       []bool
+}
+
+func (a *Adaptive) resize(n int) {
+	for _, buf := range []*[]float64{&a.x, &a.y, &a.vx, &a.vy, &a.fullX, &a.fullY} {
+		*buf = grow(*buf, n)
+	}
+	if cap(a.capped) < n {
+		a.capped = make([]bool, n)
+	}
+	a.capped = a.capped[:n]
+}
+
+func (a *Adaptive) save(nodes []Node) {
+	for i := range nodes {
+		n := &nodes[i]
+		a.x[i], a.y[i] = n.X, n.Y
+		a.vx[i], a.vy[i] = n.VelocityX, n.VelocityY
+		a.capped[i] = n.capped
+	}
+}
+
+func (a *Adaptive) restore(nodes []Node) {
+	for i := range nodes {
+		n := &nodes[i]
+		n.X, n.Y = a.x[i], a.y[i]
+		n.VelocityX, n.VelocityY = a.vx[i], a.vy[i]
+		n.capped = a.capped[i]
+	}
+}
+
+func (a *Adaptive) estimate(w *World, inner Integrator, h float64) float64 {
+	nodes := w.Nodes
+	a.save(nodes)
+	inner.Integrate(w, h)
+	for i := range nodes {
+		a.fullX[i], a.fullY[i] = nodes[i].X, nodes[i].Y
+	}
+	a.restore(nodes)
+	inner.Integrate(w, h/2)
+	inner.Integrate(w, h/2)
+	err := 0.
+	for i := range nodes {
+		err = math.Max(err, distanceXY(nodes[i].X-a.fullX[i], nodes[i].Y-a.fullY[i]))
+	}
+	a.restore(nodes)
+	return err
+}
+
+func (a *Adaptive) Integrate(w *World, duration float64) {
+	a.advance(w, duration, false)
+}
+
+func (a *Adaptive) step(w *World, duration float64) {
+	velocityCap := w.VelocityCap
+	w.VelocityCap = 0
+	a.advance(w, duration, true)
+	w.VelocityCap = velocityCap
+}
+
+func (a *Adaptive) advance(w *World, duration float64, substep bool) {
+	inner := a.Integrator
+	if inner == nil {
+		inner = SymplecticEuler{}
+	}
+	tolerance := a.Tolerance
+	if tolerance <= 0 {
+		tolerance = 1e-3
+	}
+	minDuration := a.MinDuration
+	if minDuration <= 0 {
+		minDuration = duration / 1024
+	}
+	nodes := w.Nodes
+	a.resize(len(nodes))
+	a.Substeps = 0
+	a.Error = 0
+	h := duration
+	if a.hint > 0 && a.hint < h {
+		h = a.hint
+	}
+	for remaining := duration; remaining > 0; {
+		take := h
+		if take >= remaining || remaining-take < duration*1e-9 {
+			take = remaining
+		}
+		if substep {
+			w.begin()
+		}
+		err := a.estimate(w, inner, take)
+		if err > tolerance && take > minDuration {
+			h = take / 2
+			continue
+		}
+		inner.Integrate(w, take)
+		if substep {
+			w.finish(take)
+		}
+		a.Error = math.Max(a.Error, err)
+		a.Substeps++
+		remaining -= take
+		if err < tolerance/4 && take == h {
+			h *= 2
+		}
+		a.hint = h
+	}
+}
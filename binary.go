@@ -0,0 +1,201 @@
+package springweb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	binaryMagic   = "SWB"
+	binaryVersion = 2
+)
+
+var errSnapshot = errors.New("springweb: malformed snapshot")
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) float(values ...float64) {
+	for _, v := range values {
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+	}
+}
+
+func (e *encoder) int(v int) {
+	e.buf = binary.AppendVarint(e.buf, int64(v))
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+type decoder struct {
+	buf     []byte
+	version byte
+	err     error
+}
+
+func (d *decoder) float(values ...*float64) {
+	for _, v := range values {
+		if len(d.buf) < 8 {
+			d.err = errSnapshot
+			return
+		}
+		*v = math.Float64frombits(binary.LittleEndian.Uint64(d.buf))
+		d.buf = d.buf[8:]
+	}
+}
+
+func (d *decoder) int() int {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errSnapshot
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return int(v)
+}
+
+func (d *decoder) count() int {
+	v := d.int()
+	if v < 0 || v > len(d.buf) {
+		d.err = errSnapshot
+		return 0
+	}
+	return v
+}
+
+func (d *decoder) bool() bool {
+	if len(d.buf) < 1 {
+		d.err = errSnapshot
+		return false
+	}
+	v := d.buf[0] != 0
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (arm *Arm) encode(e *encoder) {
+	e.float(arm.K, arm.w, arm.InitAngle, arm.PrevAngle, arm.prevAngleUnrest,
+		arm.lastAngleUnrest, arm.Yield, arm.Hardening, arm.plastic, arm.Damping)
+	e.int(arm.Rotations)
+}
+
+func (arm *Arm) decode(d *decoder) {
+	d.float(&arm.K, &arm.w, &arm.InitAngle, &arm.PrevAngle, &arm.prevAngleUnrest,
+		&arm.lastAngleUnrest, &arm.Yield, &arm.Hardening, &arm.plastic, &arm.Damping)
+	arm.Rotations = d.int()
+}
+
+func (s *Spring) encode(e *encoder) {
+	e.int(s.To)
+	e.float(s.K, s.Distance, s.prevDistance, s.lastDistance,
+		s.MaxTension, s.MaxCompression, s.MaxTorque,
+		s.Yield, s.Hardening, s.plastic, s.Damping)
+	s.FromArm.encode(e)
+	s.ToArm.encode(e)
+}
+
+func (s *Spring) decode(d *decoder) {
+	s.To = d.int()
+	d.float(&s.K, &s.Distance, &s.prevDistance, &s.lastDistance,
+		&s.MaxTension, &s.MaxCompression, &s.MaxTorque,
+		&s.Yield, &s.Hardening, &s.plastic, &s.Damping)
+	s.FromArm.decode(d)
+	s.ToArm.decode(d)
+}
+
+func (node *Node) encode(e *encoder) {
+	e.float(node.X, node.Y, node.R, node.M, node.VelocityX, node.VelocityY,
+		node.Angle, node.wAvgSum, node.stepX, node.stepY, node.forceX, node.forceY)
+	e.bool(node.Pinned)
+	e.bool(node.Material != nil)
+	if m := node.Material; m != nil {
+		e.float(m.Restitution, m.StaticFriction, m.KineticFriction)
+	}
+	e.int(len(node.Springs))
+	for j := range node.Springs {
+		node.Springs[j].encode(e)
+	}
+}
+
+func (node *Node) decode(d *decoder) {
+	d.float(&node.X, &node.Y, &node.R, &node.M, &node.VelocityX, &node.VelocityY,
+		&node.Angle, &node.wAvgSum, &node.stepX, &node.stepY, &node.forceX, &node.forceY)
+	node.Pinned = d.bool()
+	node.Material = nil
+	if d.version >= 2 && d.bool() {
+		node.Material = &Material{}
+		d.float(&node.Material.Restitution, &node.Material.StaticFriction, &node.Material.KineticFriction)
+	}
+	node.Springs = make([]Spring, d.count())
+	for j := range node.Springs {
+		node.Springs[j].decode(d)
+	}
+}
+
+func (w *World) MarshalBinary() ([]byte, error) {
+	e := encoder{buf: append([]byte(binaryMagic), binaryVersion)}
+	e.float(w.Time, w.dissipated, w.ArmResist, w.SpringResist,
+		w.GravityX, w.GravityY, w.MaxDuration)
+	e.int(int(w.DampingMode))
+	e.bool(w.Bounds != nil)
+	if b := w.Bounds; b != nil {
+		e.float(b.Left, b.Top, b.Right, b.Bottom, b.Bounce, b.StaticFriction, b.KineticFriction)
+	}
+	e.int(len(w.Nodes))
+	for i := range w.Nodes {
+		w.Nodes[i].encode(&e)
+	}
+	return e.buf, nil
+}
+
+func (w *World) UnmarshalBinary(data []byte) error {
+	if len(data) <= len(binaryMagic) || string(data[:len(binaryMagic)]) != binaryMagic {
+		return errSnapshot
+	}
+	d := decoder{buf: data[len(binaryMagic)+1:], version: data[len(binaryMagic)]}
+	if d.version < 1 || d.version > binaryVersion {
+		return errSnapshot
+	}
+	var v World
+	d.float(&v.Time, &v.dissipated, &v.ArmResist, &v.SpringResist,
+		&v.GravityX, &v.GravityY, &v.MaxDuration)
+	v.DampingMode = DampingMode(d.int())
+	if d.bool() {
+		v.Bounds = &Bounds{}
+		d.float(&v.Bounds.Left, &v.Bounds.Top, &v.Bounds.Right, &v.Bounds.Bottom, &v.Bounds.Bounce)
+		if d.version >= 2 {
+			d.float(&v.Bounds.StaticFriction, &v.Bounds.KineticFriction)
+		}
+	}
+	nodes := make([]Node, d.count())
+	for i := range nodes {
+		nodes[i].decode(&d)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	for i := range nodes {
+		for _, s := range nodes[i].Springs {
+			if s.To < 0 || s.To >= len(nodes) {
+				return errSnapshot
+			}
+		}
+		if i < len(w.Nodes) {
+			nodes[i].Motion = w.Nodes[i].Motion
+		}
+	}
+	w.Time, w.dissipated = v.Time, v.dissipated
+	w.ArmResist, w.SpringResist = v.ArmResist, v.SpringResist
+	w.GravityX, w.GravityY, w.MaxDuration = v.GravityX, v.GravityY, v.MaxDuration
+	w.DampingMode, w.Bounds = v.DampingMode, v.Bounds
+	w.Nodes = nodes
+	return nil
+}
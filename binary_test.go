@@ -0,0 +1,63 @@
+package springweb
+
+import "testing"
+
+func TestSnapshotResume(t *testing.T) {
+	a := randomWeb(80, 3)
+	a.Bounds = &Bounds{Left: -10, Top: -10, Right: 90, Bottom: 30, Bounce: .5, StaticFriction: .3, KineticFriction: .2}
+	a.Collisions = &Collisions{Restitution: .5}
+	a.Nodes[2].Material = &Material{Restitution: .8, StaticFriction: .5, KineticFriction: .4}
+	a.Nodes[4].Pinned = true
+	for step := 0; step < 200; step++ {
+		a.Step(.01)
+	}
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewWorld(nil)
+	b.Collisions = &Collisions{Restitution: .5}
+	if err := b.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for step := 0; step < 300; step++ {
+		a.Step(.01)
+		b.Step(.01)
+	}
+	for i := range a.Nodes {
+		m, n := &a.Nodes[i], &b.Nodes[i]
+		if m.X != n.X || m.Y != n.Y || m.VelocityX != n.VelocityX || m.VelocityY != n.VelocityY || m.Angle != n.Angle {
+			t.Fatalf("node %d differs after resume", i)
+		}
+	}
+	if a.Energy() != b.Energy() || a.Time != b.Time {
+		t.Fatal("energy or time differs after resume")
+	}
+	if err := b.UnmarshalBinary(data[:len(data)-3]); err == nil {
+		t.Fatal("truncated snapshot accepted")
+	}
+}
+
+func TestSnapshotKeepsReceiver(t *testing.T) {
+	a := randomWeb(20, 3)
+	a.ArmResist = .5
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := randomWeb(20, 4)
+	track := Track{{Time: 0, X: 1, Y: 2}, {Time: 1, X: 3, Y: 2}}
+	b.Nodes[6].Motion = track
+	if err := b.UnmarshalBinary(data[:len(data)/2]); err == nil {
+		t.Fatal("truncated snapshot accepted")
+	}
+	if b.ArmResist != ArmResist || b.Nodes[0].X == a.Nodes[0].X {
+		t.Fatal("failed decode changed the world")
+	}
+	if err := b.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if b.ArmResist != .5 || b.Nodes[6].Motion == nil {
+		t.Fatal("decode dropped the snapshot or the node motion")
+	}
+}
@@ -0,0 +1,52 @@
+package springweb
+
+import "math"
+
+type Break struct {
+	From, To      int
+	Force, Torque float64
+	Time          float64
+}
+
+func (s *Spring) strain(node, to *Node) (force, torque float64) {
+	force = s.K * (distance(node, to) - s.Distance)
+	torque = s.FromArm.K * s.FromArm.unrest(node, to)
+	if t := s.ToArm.K * s.ToArm.unrest(to, node); math.Abs(t) > math.Abs(torque) {
+		torque = t
+	}
+	return
+}
+
+func (s *Spring) breaks(force, torque float64) bool {
+	if s.MaxTension > 0 && force > s.MaxTension {
+		return true
+	}
+	if s.MaxCompression > 0 && -force > s.MaxCompression {
+		return true
+	}
+	return s.MaxTorque > 0 && math.Abs(torque) > s.MaxTorque
+}
+
+func (w *World) breakSprings(time float64) {
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		springs := n.Springs[:0]
+		for _, s := range n.Springs {
+			if s.MaxTension == 0 && s.MaxCompression == 0 && s.MaxTorque == 0 {
+				springs = append(springs, s)
+				continue
+			}
+			force, torque := s.strain(n, &w.Nodes[s.To])
+			if !s.breaks(force, torque) {
+				springs = append(springs, s)
+				continue
+			}
+			b := Break{From: i, To: s.To, Force: force, Torque: torque, Time: time}
+			w.Breaks = append(w.Breaks, b)
+			if w.OnBreak != nil {
+				w.OnBreak(b)
+			}
+		}
+		n.Springs = springs
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/biotty/springweb"
+)
+
+const (
+	spacing      = 10.
+	radius       = 3.
+	mass         = 1e-2
+	k            = 1.
+	armK         = 1e3
+	stepDuration = 1. / 120
+)
+
+func grid(nodes int) *springweb.World {
+	side := int(math.Ceil(math.Sqrt(float64(nodes))))
+	w := springweb.NewWorld(make([]springweb.Node, 0, nodes))
+	for i := 0; i < nodes; i++ {
+		w.AddNode(springweb.NewNode(float64(i%side)*spacing, float64(i/side)*spacing, radius, mass))
+	}
+	for i := 0; i < nodes; i++ {
+		if i%side != 0 {
+			w.AddSpring(i, i-1, k, armK)
+		}
+		if i >= side {
+			w.AddSpring(i, i-side, k, armK)
+			if i%side != 0 {
+				w.AddSpring(i, i-side-1, k*.5, armK)
+			}
+		}
+	}
+	w.GravityY = 9.8
+	w.Prepare()
+	return w
+}
+
+func springs(w *springweb.World) int {
+	n := 0
+	for i := range w.Nodes {
+		n += len(w.Nodes[i].Springs)
+	}
+	return n
+}
+
+func measure(steps int, step func()) (perStep time.Duration, allocs float64) {
+	step()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < steps; i++ {
+		step()
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return elapsed / time.Duration(steps), float64(after.Mallocs-before.Mallocs) / float64(steps)
+}
+
+func main() {
+	sizes := flag.String("sizes", "1000,4000,16000,64000", "comma separated node counts")
+	steps := flag.Int("steps", 100, "steps per measurement")
+	workers := flag.Int("workers", runtime.NumCPU(), "goroutines for the parallel layout")
+	flag.Parse()
+	fmt.Printf("%-8s %8s %8s %12s %12s %10s\n", "layout", "nodes", "springs", "ns/step", "ns/node", "allocs")
+	for _, field := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n <= 0 {
+			fmt.Fprintln(os.Stderr, "springweb-bench: bad size", field)
+			os.Exit(2)
+		}
+		nodes := grid(n)
+		parallel := grid(n)
+		parallel.Workers = *workers
+		packed, err := springweb.NewPacked(grid(n))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "springweb-bench:", err)
+			os.Exit(1)
+		}
+		layouts := []struct {
+			name string
+			step func()
+		}{
+			{"nodes", func() { nodes.Step(stepDuration) }},
+			{"parallel", func() { parallel.Step(stepDuration) }},
+			{"packed", func() { packed.Step(stepDuration) }},
+		}
+		for _, l := range layouts {
+			perStep, allocs := measure(*steps, l.step)
+			fmt.Printf("%-8s %8d %8d %12d %12.1f %10.1f\n", l.name, n, springs(nodes),
+				perStep.Nanoseconds(), float64(perStep.Nanoseconds())/float64(n), allocs)
+		}
+	}
+}
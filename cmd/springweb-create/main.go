@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"math"
 	"syscall/js"
 	"time"
@@ -19,6 +20,13 @@ const (
 	maxDriveAngleVelocity = 1e1
 	sizeFactor        = 5e-2
 	sizeButtonClick   = 5
+	stepDuration      = 1. / 120
+	maxSubsteps       = 8
+	modeCount         = 8
+	modePeriod        = 1.5
+	borderBounce      = .65
+	borderFriction    = .3
+	velocityCap       = 2
 	voidColor         = "#ffd"
 	barColor          = "#bd3"
 	buttonColor       = "#451"
@@ -31,13 +39,12 @@ const (
 func (a *anim) newDot(x, y float64) {
 	m := a.lastMass()
 	r := a.dotRadius(m)
-	a.dots[a.nDots] = springweb.NewNode(x, y, r, m)
-	a.nDots++
+	a.web.AddNode(springweb.NewNode(x, y, r, m))
 }
 
 func (a *anim) newLine(i, j int) {
 	k := a.lastK()
-	a.dots[i].NewSpring(&a.dots[j], k, armKFactor*k)
+	a.web.AddSpring(i, j, k, armKFactor*k)
 }
 
 func (a *anim) findDot(x, y float64) int {
@@ -45,8 +52,8 @@ func (a *anim) findDot(x, y float64) int {
 	if !a.running {
 		outsideAllow = 1.3
 	}
-	for i := 0; i < a.nDots; i++ {
-		d := a.dots[i]
+	for i := 0; i < len(a.web.Nodes); i++ {
+		d := a.web.Nodes[i]
 		r := a.dotRadius(d.M) * outsideAllow
 		if math.Pow(x-d.X, 2)+math.Pow(y-d.Y, 2) <= math.Pow(r, 2) {
 			return i
@@ -57,17 +64,24 @@ func (a *anim) findDot(x, y float64) int {
 
 type anim struct {
 	width, height, dotSize float64
-	dots, resetNodes       []springweb.Node
-	nDots                  int
+	web                    springweb.Web
+	reset                  *springweb.Web
 	selectedDot            int
-	dragging               bool
+	grab                   springweb.Grab
 	ctx                    js.Value
 	images                 []js.Value
 	callback               js.Func
 	lastCall               time.Time
-	deltaT                 float64
 	running                bool
 	keyisdown              bool
+	runner                 *springweb.Runner
+	recording              *springweb.Recording
+	frames                 []springweb.Frame
+	modes                  []springweb.Mode
+	mode                   int
+	modeStart              time.Time
+	modeCallback           js.Func
+	caps                   int
 }
 
 func (a *anim) buttonHeight() float64 {
@@ -131,51 +145,67 @@ func (a *anim) clear() {
 	a.drawBar()
 }
 
+func (a *anim) position(i int) (x, y float64) {
+	d := &a.web.Nodes[i]
+	if a.running {
+		return d.Interpolate(a.runner.Alpha())
+	}
+	if a.mode >= 0 {
+		m := &a.modes[a.mode]
+		phase := 2 * math.Pi * time.Since(a.modeStart).Seconds() / modePeriod
+		amplitude := a.dotSize * math.Sin(phase)
+		return d.X + m.X[i]*amplitude, d.Y + m.Y[i]*amplitude
+	}
+	return d.X, d.Y
+}
+
 func (a *anim) drawDot(i int) {
-	d := a.dots[i]
+	d := a.web.Nodes[i]
+	x, y := a.position(i)
 	if !a.running || i == a.selectedDot {
 		r := d.R
 		if a.running {
 			r *= 1.1
 		}
 		a.ctx.Call("beginPath")
-		a.ctx.Call("arc", d.X, d.Y, r, 0, math.Pi*2)
+		a.ctx.Call("arc", x, y, r, 0, math.Pi*2)
 		a.ctx.Call("fill")
 		a.ctx.Call("closePath")
 	}
 	if a.running {
 		img := a.images[i%2]
 		a.ctx.Call("save")
-		a.ctx.Call("translate", d.X, d.Y)
+		a.ctx.Call("translate", x, y)
 		a.ctx.Call("rotate", d.Angle)
 		a.ctx.Call("drawImage", img, -d.R, -d.R, d.R*2, d.R*2)
 		a.ctx.Call("restore")
 	}
 }
 
-func (a *anim) drawLineTo(i int, x, y, k float64) {
-	d := a.dots[i]
+func (a *anim) drawLineTo(i, j int, k float64) {
+	fromX, fromY := a.position(i)
+	x, y := a.position(j)
 	a.ctx.Set("lineWidth", a.lineWidth(k))
 	a.ctx.Call("beginPath")
-	a.ctx.Call("moveTo", d.X, d.Y)
+	a.ctx.Call("moveTo", fromX, fromY)
 	a.ctx.Call("lineTo", x, y)
 	a.ctx.Call("stroke")
 }
 
 func (a *anim) drawWeb() {
 	a.clear()
-	for i := 0; i < a.nDots; i++ {
-		from := a.dots[i]
+	for i := 0; i < len(a.web.Nodes); i++ {
+		from := a.web.Nodes[i]
 		if i == a.selectedDot && !a.running {
 			a.ctx.Set("strokeStyle", selectedLineColor)
 		} else {
 			a.ctx.Set("strokeStyle", lineColor)
 		}
 		for _, s := range from.Springs {
-			a.drawLineTo(i, s.To.X, s.To.Y, s.K)
+			a.drawLineTo(i, s.To, s.K)
 		}
 	}
-	for i := 0; i < a.nDots; i++ {
+	for i := 0; i < len(a.web.Nodes); i++ {
 		if i == a.selectedDot {
 			a.ctx.Set("fillStyle", selectedDotColor)
 		} else {
@@ -185,29 +215,6 @@ func (a *anim) drawWeb() {
 	}
 }
 
-func (a *anim) borderStep() {
-	const bounceFactor float64 = -.65
-	for i := 0; i < a.nDots; i++ {
-		d := &a.dots[i]
-		if d.VelocityX < 0 && d.X < d.R {
-			d.VelocityX *= bounceFactor
-			d.X = d.R
-		}
-		if d.VelocityY < 0 && d.Y < a.buttonHeight()+d.R {
-			d.VelocityY *= bounceFactor
-			d.Y = a.buttonHeight() + d.R
-		}
-		if d.VelocityX > 0 && d.X > a.width-d.R {
-			d.VelocityX *= bounceFactor
-			d.X = a.width - d.R
-		}
-		if d.VelocityY > 0 && d.Y > a.height-d.R {
-			d.VelocityY *= bounceFactor
-			d.Y = a.height - d.R
-		}
-	}
-}
-
 func newAnim(width, height, dotSize float64, nNodes int) *anim {
 	doc := js.Global().Get("document")
 	elem := doc.Call("createElement", "canvas")
@@ -220,68 +227,145 @@ func newAnim(width, height, dotSize float64, nNodes int) *anim {
 	}
 	ctx := elem.Call("getContext", "2d")
 	a := anim{width, height, dotSize,
-		make([]springweb.Node, nNodes), nil, 0, 0, false,
-		ctx, images, js.Func{}, time.Time{}, 0, false, false}
+		springweb.Web{Nodes: make([]springweb.Node, 0, nNodes)}, nil, 0, springweb.Grab{},
+		ctx, images, js.Func{}, time.Time{}, false, false, nil, nil, nil,
+		nil, -1, time.Time{}, js.Func{}, 0}
 	a.clear()
 	a.callback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		if !a.running {
 			return nil
 		}
 		t := time.Now()
-		if a.running {
-			x, y := a.draggedDotPosition()
-			deltaT := 1e-9*float64(t.Sub(a.lastCall))
-			if a.deltaT == 0 || deltaT < .3 {
-				a.deltaT = deltaT
-			}
-			springweb.Step(a.dots[:a.nDots], a.deltaT)
-			a.borderStep()
-			a.positionDraggedDot(x, y)
-			a.drawWeb()
-		}
+		a.advance(1e-9 * float64(t.Sub(a.lastCall)))
+		a.drawWeb()
 		a.lastCall = t
 		js.Global().Call("requestAnimationFrame", a.callback)
 		return nil
 	})
+	a.modeCallback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if a.running || a.mode < 0 {
+			return nil
+		}
+		a.drawWeb()
+		js.Global().Call("requestAnimationFrame", a.modeCallback)
+		return nil
+	})
 	return &a
 }
 
+func (a *anim) nextMode() {
+	if a.mode < 0 {
+		web := a.web.Clone()
+		w := springweb.NewWorld(web.Nodes)
+		w.Prepare()
+		a.modes = w.Modes(modeCount)
+		a.modeStart = time.Now()
+		js.Global().Call("requestAnimationFrame", a.modeCallback)
+	}
+	a.mode++
+	if a.mode == len(a.modes) {
+		a.stopMode()
+		return
+	}
+	log("mode", a.mode, "frequency", a.modes[a.mode].Frequency)
+}
+
+func (a *anim) stopMode() {
+	a.mode = -1
+	a.modes = nil
+	a.drawWeb()
+}
+
+func (a *anim) advance(elapsed float64) {
+	if a.frames == nil {
+		if a.recording != nil {
+			a.recording.Frame(elapsed)
+		}
+		a.runner.Advance(elapsed)
+		a.reportCaps()
+		return
+	}
+	f := a.frames[0]
+	a.frames = a.frames[1:]
+	if len(a.frames) == 0 {
+		a.frames = nil
+	}
+	for _, e := range f.Events {
+		a.apply(e)
+	}
+	a.runner.Advance(f.Elapsed)
+	a.reportCaps()
+}
+
+func (a *anim) capped(node int) {
+	a.caps++
+}
+
+func (a *anim) reportCaps() {
+	if a.caps > 0 {
+		log("velocity capped", a.caps, "times")
+		a.caps = 0
+	}
+}
+
+func (a *anim) run() {
+	a.running = true
+	a.grab = springweb.Grab{Dot: a.selectedDot}
+	a.runner.OnStep = a.dragStep
+	a.runner.World.OnCap = a.capped
+	a.lastCall = time.Now()
+	js.Global().Call("requestAnimationFrame", a.callback)
+}
+
 func (a *anim) toggleRunEdit() {
-	a.running = !a.running
-	if a.running {
-		a.resetNodes = make([]springweb.Node, a.nDots)
-		copy(a.resetNodes, a.dots)
-		springweb.StepsPrepare(a.dots[:a.nDots])
-		a.lastCall = time.Now()
-		js.Global().Call("requestAnimationFrame", a.callback)
+	if !a.running {
+		a.mode = -1
+		a.reset = a.web.Clone()
+		w := springweb.NewWorld(a.web.Nodes)
+		w.Bounds = &springweb.Bounds{Left: 0, Top: a.buttonHeight(),
+			Right: a.width, Bottom: a.height, Bounce: borderBounce,
+			StaticFriction: borderFriction, KineticFriction: borderFriction}
+		w.Collisions = &springweb.Collisions{Restitution: borderBounce, Continuous: true,
+			Segments: true, SpringThickness: a.lineWidth(defaultK)}
+		w.VelocityCap = velocityCap
+		w.Prepare()
+		if stable := w.StableDuration(); stepDuration > stable {
+			log("step", stepDuration, "exceeds the stable step", stable)
+		}
+		a.runner = springweb.NewRunner(w, stepDuration, maxSubsteps)
+		a.recording, _ = springweb.NewRecording(a.runner, 0)
+		a.recording.Program = "springweb-create"
+		a.frames = nil
+		a.run()
 	} else {
-		copy(a.dots, a.resetNodes)
-		a.selectedDot = a.nDots - 1
+		a.running = false
+		a.web.Restore(a.reset)
+		a.selectedDot = len(a.web.Nodes) - 1
 		a.drawWeb()
 	}
 }
 
 func (a *anim) editClickVoid(x, y float64) {
-	if a.nDots == len(a.dots) {
+	if len(a.web.Nodes) == cap(a.web.Nodes) {
 		return
 	}
-	a.selectedDot = a.nDots
+	a.selectedDot = len(a.web.Nodes)
 	a.newDot(x, y)
 	a.drawWeb()
 }
 
 func (a *anim) editClickDot(i int) {
-	j := a.nDots - 1
-	d := &a.dots[j]
+	j := len(a.web.Nodes) - 1
+	d := &a.web.Nodes[j]
 	if i == j {
-		a.nDots--
+		a.web.Nodes = a.web.Nodes[:j]
 		if j != 0 {
 			a.selectedDot = j - 1
 		}
 		return
 	}
 	for k, _ := range d.Springs {
-		if d.Springs[k].To == &a.dots[i] {
+		if d.Springs[k].To == i {
 			d.Springs = append(d.Springs[:k], d.Springs[k+1:]...)
 			return
 		}
@@ -290,8 +374,8 @@ func (a *anim) editClickDot(i int) {
 }
 
 func (a *anim) lastK() float64 {
-	for i := a.nDots - 1; i >= 0; i-- {
-		s := a.dots[i].Springs
+	for i := len(a.web.Nodes) - 1; i >= 0; i-- {
+		s := a.web.Nodes[i].Springs
 		n := len(s)
 		if n != 0 {
 			return s[n-1].K
@@ -301,61 +385,63 @@ func (a *anim) lastK() float64 {
 }
 
 func (a *anim) lastMass() float64 {
-	if a.nDots == 0 {
+	if len(a.web.Nodes) == 0 {
 		return defaultMass
 	}
-	return a.dots[a.nDots-1].M
+	return a.web.Nodes[len(a.web.Nodes)-1].M
 }
 
-func (a *anim) clickRunning(x, y float64) {
-	if i := a.findDot(x, y); i >= 0 {
-		a.selectedDot = i
+func (a *anim) apply(e springweb.Event) {
+	a.grab.Apply(a.runner.World, e)
+	if e.Kind != springweb.EventRelease {
+		a.selectedDot = e.Dot
 	}
-	a.dragging = true
-	a.positionDraggedDot(x, y)
 }
 
-func (a *anim) positionDraggedDot(x, y float64) {
-	if a.dragging {
-		node := &a.dots[a.selectedDot]
-		if a.deltaT > 0 {
-			node.VelocityX = .1 * (9*node.VelocityX + (x-node.X)/a.deltaT)
-			node.VelocityY = .1 * (9*node.VelocityY + (y-node.Y)/a.deltaT)
-		}
-		node.X = x
-		node.Y = y
+func (a *anim) input(e springweb.Event) {
+	if a.frames != nil {
+		return // replaying
+	}
+	a.apply(e)
+	if a.recording != nil {
+		a.recording.Event(e)
 	}
 }
 
-func (a *anim) draggedDotPosition() (x, y float64) {
-	if a.dragging {
-		node := a.dots[a.selectedDot]
-		x = node.X
-		y = node.Y
+func (a *anim) clickRunning(x, y float64) {
+	i := a.findDot(x, y)
+	if i < 0 {
+		i = a.selectedDot
 	}
-	return
+	a.input(springweb.Event{Kind: springweb.EventDrag, Dot: i, X: x, Y: y})
+}
+
+func (a *anim) dragStep(duration float64) {
+	a.grab.Step(a.runner.World, duration)
 }
 
 func (a *anim) dotSelect(z float64) {
+	i := a.selectedDot
 	if z > 0 {
-		a.selectedDot++
-		if a.selectedDot == a.nDots {
-			a.selectedDot = 0
+		i++
+		if i == len(a.web.Nodes) {
+			i = 0
 		}
 	} else {
-		if a.selectedDot == 0 {
-			a.selectedDot = a.nDots
+		if i == 0 {
+			i = len(a.web.Nodes)
 		}
-		a.selectedDot--
+		i--
 	}
+	a.input(springweb.Event{Kind: springweb.EventSelect, Dot: i})
 }
 
 func (a *anim) sizeCurrent(z float64) {
-	if a.nDots <= 0 {
+	if len(a.web.Nodes) <= 0 {
 		return
 	}
-	j := a.nDots - 1
-	d := a.dots[j]
+	j := len(a.web.Nodes) - 1
+	d := a.web.Nodes[j]
 	n := len(d.Springs)
 	if n != 0 {
 		s := &d.Springs[n-1]
@@ -364,7 +450,7 @@ func (a *anim) sizeCurrent(z float64) {
 			s.K = k
 		}
 	} else {
-		d := &a.dots[j]
+		d := &a.web.Nodes[j]
 		w := d.M / (1 + z*sizeFactor)
 		if w >= minMass && w <= maxMass {
 			d.M = w
@@ -375,6 +461,9 @@ func (a *anim) sizeCurrent(z float64) {
 }
 
 func (a *anim) upDown(z float64) {
+	if a.mode >= 0 {
+		a.stopMode()
+	}
 	if a.running {
 		a.dotSelect(z)
 	} else {
@@ -393,6 +482,9 @@ func (a *anim) clickButton(x float64) {
 }
 
 func (a *anim) click(event js.Value) {
+	if a.mode >= 0 {
+		a.stopMode()
+	}
 	x := event.Get("clientX").Float()
 	y := event.Get("clientY").Float()
 	if y < a.buttonHeight() {
@@ -421,7 +513,9 @@ func (a *anim) wheel(event js.Value) {
 }
 
 func (a *anim) pointerRelease(event js.Value) {
-	a.dragging = false
+	if a.running && a.grab.Active {
+		a.input(springweb.Event{Kind: springweb.EventRelease})
+	}
 }
 
 func (a *anim) pointerMove(event js.Value) {
@@ -429,9 +523,9 @@ func (a *anim) pointerMove(event js.Value) {
 		return
 	}
 	x := event.Get("clientX").Float()
-	if a.dragging {
+	if a.grab.Active {
 		y := event.Get("clientY").Float()
-		a.positionDraggedDot(x, y)
+		a.input(springweb.Event{Kind: springweb.EventDrag, Dot: a.selectedDot, X: x, Y: y})
 	}
 }
 
@@ -454,9 +548,57 @@ func (a *anim) keydown(event js.Value) {
 	case "ArrowRight":
 		event.Call("preventDefault")
 		a.toggleRunEdit()
+	case "KeyM":
+		if !a.running && len(a.web.Nodes) != 0 {
+			a.nextMode()
+		}
 	}
 }
 
+func (a *anim) recordingJSON() string {
+	if a.recording == nil {
+		return ""
+	}
+	data, err := json.Marshal(a.recording)
+	if err != nil {
+		log(err.Error())
+		return ""
+	}
+	return string(data)
+}
+
+func (a *anim) replay(data string) {
+	var rec springweb.Recording
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		log(err.Error())
+		return
+	}
+	w := springweb.NewWorld(nil)
+	runner, err := rec.Runner(w)
+	if err != nil {
+		log(err.Error())
+		return
+	}
+	if len(w.Nodes) > cap(a.web.Nodes) {
+		log("replay: too many dots")
+		return
+	}
+	if a.running {
+		a.toggleRunEdit()
+	}
+	a.web.Restore(&w.Web)
+	w.Nodes = a.web.Nodes
+	a.reset = a.web.Clone()
+	a.runner = runner
+	a.recording = nil
+	a.frames = rec.Frames
+	if len(a.frames) == 0 {
+		a.frames = nil
+	}
+	a.selectedDot = len(a.web.Nodes) - 1
+	a.run()
+}
+
 func main() {
 	height := js.Global().Get("innerHeight").Float() - 24
 	width := js.Global().Get("innerWidth").Float() - 24
@@ -478,6 +620,16 @@ func main() {
 			}))
 	}
 
+	js.Global().Set("springwebRecording",
+		js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return a.recordingJSON()
+		}))
+	js.Global().Set("springwebReplay",
+		js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			a.replay(args[0].String())
+			return nil
+		}))
+
 	<-make(chan bool)
 }
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"math"
 	"math/rand"
 	"syscall/js"
@@ -18,9 +19,12 @@ const (
 	minMass             = defaultMass * .25
 	maxMass             = defaultMass * 5
 	platformBounce      = .5
+	platformFriction    = .5
 	platformSpeed       = 9
 	platformStick       = .3
 	gravity             = 7e2
+	stepDuration        = 1. / 120
+	maxSubsteps         = 8
 	maxWheelForce       = 1.1
 	maxWheelVelocity    = 1e1
 	wheelGyrationFactor = 1
@@ -44,8 +48,7 @@ func (a *anim) vary() float64 {
 func (a *anim) newDotM(x, y, m float64) {
 	m *= a.vary()
 	r := a.dotRadius(m)
-	a.dots[a.nDots] = springweb.NewNode(x, y, r, m)
-	a.nDots++
+	a.web.AddNode(springweb.NewNode(x, y, r, m))
 }
 
 func (a *anim) newDot(x, y float64) {
@@ -54,11 +57,11 @@ func (a *anim) newDot(x, y float64) {
 
 func (a *anim) newLineK(i, j int, k float64) {
 	k *= a.vary()
-	a.dots[i].NewSpring(&a.dots[j], k, armKFactor*k)
+	a.web.AddSpring(i, j, k, armKFactor*k)
 }
 
 func (a *anim) newLine(i, j int) {
-	if i <= j { // contract: springweb
+	if i == j {
 		return
 	}
 	a.newLineK(i, j, defaultK)
@@ -103,12 +106,11 @@ func (p *platform) onHeight(d *springweb.Node) float64 {
 	return h
 }
 
+var platformMaterial = springweb.Material{Restitution: platformBounce,
+	StaticFriction: platformFriction, KineticFriction: platformFriction}
+
 func (p *platform) bounce(d *springweb.Node, depth float64) {
-	vProd := d.VelocityX*p.surfaceX + d.VelocityY*p.surfaceY
-	if vProd < 0 {
-		d.VelocityX = platformBounce * (vProd*-2*p.surfaceX + d.VelocityX)
-		d.VelocityY = platformBounce * (vProd*-2*p.surfaceY + d.VelocityY)
-	}
+	d.Collide(p.surfaceX, p.surfaceY, &platformMaterial)
 	d.X += depth * p.surfaceX
 	d.Y += depth * p.surfaceY
 }
@@ -121,8 +123,7 @@ type wheel struct {
 
 type anim struct {
 	width, height, dotSize float64
-	dots                   []springweb.Node
-	nDots                  int
+	web                    springweb.Web
 	iLetterDots            int
 	nCarDots               int
 	ctx                    js.Value
@@ -140,24 +141,20 @@ type anim struct {
 	nLetterAliens          int
 	haveLetters            []bool
 	rands                  *rand.Rand
+	seed                   int64
+	runner                 *springweb.Runner
+	recording              *springweb.Recording
+	frames                 []springweb.Frame
+	looping                bool
 }
 
 func (a *anim) setCallback() {
 	a.callback = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		t := time.Now()
-
-		deltaT := 1e-9 * float64(t.Sub(a.lastCall))
-		if deltaT < .3 || a.deltaT == 0 {
-			a.deltaT = deltaT
-		}
+		a.advance(1e-9 * float64(t.Sub(a.lastCall)))
 		a.lastCall = t
 
-		springweb.Step(a.dots[:a.nDots], a.deltaT)
-		a.wheelsStep()
 		a.lettersStep()
-		a.platformsStep(deltaT)
-		a.gravityStep()
-		a.viewBorderStep()
 		a.viewScrollStep()
 		a.worldCycle()
 		a.drawView()
@@ -181,19 +178,56 @@ func newAnim(width, height, dotSize float64, nNodes int) *anim {
 	ctx := elem.Call("getContext", "2d")
 	ctx.Set("font", "15px Arial")
 	a := anim{width, height, dotSize,
-		make([]springweb.Node, nNodes), 0, 0, 0,
+		springweb.Web{Nodes: make([]springweb.Node, 0, nNodes)}, 0, 0,
 		ctx, images, js.Func{}, time.Time{}, 0, 0, 0,
 		nil, 2, nil, 15, nil, 7, nil,
-		rand.New(rand.NewSource(time.Now().UnixNano()))}
-
-	a.wheels = make([]wheel, a.nWheels)
-	a.platforms = make([]platform, a.nPlatforms)
-	a.alienLetters = make([]int, a.nLetterAliens)
-	a.haveLetters = make([]bool, 26)
+		nil, 0, nil, nil, nil, false}
 	a.setCallback()
 	return &a
 }
 
+func (a *anim) advance(elapsed float64) {
+	if a.frames == nil {
+		if a.recording != nil {
+			a.recording.Frame(elapsed)
+		}
+		a.runner.Advance(elapsed)
+		return
+	}
+	f := a.frames[0]
+	a.frames = a.frames[1:]
+	if len(a.frames) == 0 {
+		a.frames = nil
+	}
+	for _, e := range f.Events {
+		a.apply(e)
+	}
+	a.runner.Advance(f.Elapsed)
+}
+
+func (a *anim) apply(e springweb.Event) {
+	if e.Kind == springweb.EventForce {
+		a.wheelForce = e.Value
+	}
+}
+
+func (a *anim) input(e springweb.Event) {
+	if a.frames != nil {
+		return // replaying
+	}
+	a.apply(e)
+	if a.recording != nil {
+		a.recording.Event(e)
+	}
+}
+
+func (a *anim) substep(duration float64) {
+	a.deltaT = duration
+	a.wheelsStep()
+	a.platformsStep(duration)
+	a.viewBorderStep()
+}
+
 func (a *anim) barHeight() float64 {
 	return a.dotSize * 2
 }
@@ -254,14 +288,21 @@ func (a *anim) clear() {
 	a.drawBar()
 }
 
+func (a *anim) position(d *springweb.Node) (x, y float64) {
+	x, y = d.Interpolate(a.runner.Alpha())
+	x -= a.viewX
+	return
+}
+
 func (a *anim) drawDot(i int) {
-	d := a.dots[i]
+	d := a.web.Nodes[i]
+	x, y := a.position(&d)
 	b := d.Angle
 	if i >= a.iLetterDots {
 		r := d.R
 		a.ctx.Set("fillStyle", letterCupColor)
 		a.ctx.Call("beginPath")
-		a.ctx.Call("arc", d.X-a.viewX, d.Y, r, b, math.Pi+b)
+		a.ctx.Call("arc", x, y, r, b, math.Pi+b)
 		a.ctx.Call("fill")
 		a.ctx.Call("closePath")
 	}
@@ -271,7 +312,7 @@ func (a *anim) drawDot(i int) {
 		s := d.R / 9
 		a.ctx.Set("fillStyle", letterColor)
 		a.ctx.Call("save")
-		a.ctx.Call("setTransform", s, 0, 0, s, d.X-a.viewX, d.Y)
+		a.ctx.Call("setTransform", s, 0, 0, s, x, y)
 		a.ctx.Call("rotate", b)
 		a.ctx.Call("fillText", text, -5, 4)
 		a.ctx.Call("restore")
@@ -282,31 +323,32 @@ func (a *anim) drawDot(i int) {
 		b += a.wheels[i].angle // alt: =
 	}
 	a.ctx.Call("save")
-	a.ctx.Call("translate", d.X-a.viewX, d.Y)
+	a.ctx.Call("translate", x, y)
 	a.ctx.Call("rotate", b)
 	a.ctx.Call("drawImage", img, -d.R, -d.R, d.R*2, d.R*2)
 	a.ctx.Call("restore")
 }
 
-func (a *anim) drawLineTo(i int, x, y, k float64) {
-	d := a.dots[i]
+func (a *anim) drawLineTo(i int, to *springweb.Node, k float64) {
+	fromX, fromY := a.position(&a.web.Nodes[i])
+	x, y := a.position(to)
 	a.ctx.Set("lineWidth", a.lineWidth(k))
 	a.ctx.Call("beginPath")
-	a.ctx.Call("moveTo", d.X-a.viewX, d.Y)
-	a.ctx.Call("lineTo", x-a.viewX, y)
+	a.ctx.Call("moveTo", fromX, fromY)
+	a.ctx.Call("lineTo", x, y)
 	a.ctx.Call("stroke")
 }
 
 func (a *anim) drawView() {
 	a.clear()
 	a.ctx.Set("strokeStyle", lineColor)
-	for i := 0; i < a.nDots; i++ {
-		from := a.dots[i]
+	for i := 0; i < len(a.web.Nodes); i++ {
+		from := a.web.Nodes[i]
 		for _, s := range from.Springs {
-			a.drawLineTo(i, s.To.X, s.To.Y, s.K)
+			a.drawLineTo(i, &a.web.Nodes[s.To], s.K)
 		}
 	}
-	for i := 0; i < a.nDots; i++ {
+	for i := 0; i < len(a.web.Nodes); i++ {
 		a.drawDot(i)
 	}
 
@@ -327,8 +369,8 @@ func (a *anim) drawPlatforms() {
 }
 
 func (a *anim) platformsStep(deltaT float64) {
-	for i := 0; i < a.nDots; i++ {
-		d := &a.dots[i]
+	for i := 0; i < len(a.web.Nodes); i++ {
+		d := &a.web.Nodes[i]
 		if i >= a.nWheels && i < a.iLetterDots {
 			continue
 		}
@@ -353,13 +395,11 @@ func (a *anim) platformsStep(deltaT float64) {
 	}
 }
 
-func (a *anim) gravityStep() {
-	for i := 0; i < a.nDots; i++ {
-		d := &a.dots[i]
-		if d.Y < a.height-d.R {
-			d.VelocityY += gravity * a.deltaT
-		}
+func (a *anim) gravityField(d *springweb.Node, time float64) (x, y float64) {
+	if d.Y < a.height-d.R {
+		y = d.M * gravity
 	}
+	return
 }
 
 func (a *anim) wheelVelocityBelowMax(velocityX float64) bool {
@@ -373,7 +413,7 @@ func (a *anim) wheelVelocityBelowMax(velocityX float64) bool {
 }
 
 func (a *anim) wheelRotation(i int) {
-	d := &a.dots[i]
+	d := &a.web.Nodes[i]
 	w := &a.wheels[i]
 	j := w.onPlatform
 	if j == a.nPlatforms { // off-by-1: on the ground
@@ -409,11 +449,11 @@ func (a *anim) wheelsStep() {
 }
 
 func (a *anim) lettersStep() {
-	for i := a.iLetterDots; i < a.nDots; i++ {
+	for i := a.iLetterDots; i < len(a.web.Nodes); i++ {
 		u := a.alienLetters[i-a.iLetterDots]
 		if !a.haveLetters[u] {
-			d := &a.dots[i]
-			c := &a.dots[a.nCarDots-1]
+			d := &a.web.Nodes[i]
+			c := &a.web.Nodes[a.nCarDots-1]
 			if distanceXY(c.X-d.X, c.Y-d.Y) < a.dotSize*3 {
 				a.haveLetters[u] = true
 			}
@@ -423,7 +463,7 @@ func (a *anim) lettersStep() {
 
 func (a *anim) viewBorderStep() {
 	for i := 0; i < a.nCarDots; i++ {
-		d := &a.dots[i]
+		d := &a.web.Nodes[i]
 		if d.VelocityX < 0 && d.X < a.viewX+d.R {
 			d.VelocityX *= -platformBounce
 			d.X = a.viewX + d.R
@@ -439,10 +479,10 @@ func (a *anim) viewBorderStep() {
 }
 
 func (a *anim) viewScrollStep() {
-	if a.nDots == 0 {
+	if len(a.web.Nodes) == 0 {
 		return
 	}
-	x := .5 * (a.dots[0].X + a.dots[1].X)
+	x := .5 * (a.web.Nodes[0].X + a.web.Nodes[1].X)
 	q := a.width * .5
 	if x > a.viewX+q {
 		a.viewX = x - q
@@ -459,18 +499,18 @@ func (a *anim) platformCycle(i int) {
 }
 
 func (a *anim) alienCycle(i int) {
-	d := &a.dots[i]
+	d := &a.web.Nodes[i]
 	a.alienLetters[i-a.iLetterDots] = a.rands.Intn(len(a.haveLetters))
 	x := a.viewX + (1+a.rands.Float64())*a.width + a.dotSize
 	y := 0.
 	h := a.dotSize * 2
 	for d != nil {
-		d.X = x + a.rands.Float64()*a.dotSize*.125
-		d.Y = y + a.rands.Float64()*a.dotSize*.125
+		d.Place(x+a.rands.Float64()*a.dotSize*.125,
+			y+a.rands.Float64()*a.dotSize*.125)
 		y += h
 		if len(d.Springs) != 0 {
 			d.Springs[0].Distance = h
-			d = d.Springs[0].To // fornow: assume only one spring
+			d = &a.web.Nodes[d.Springs[0].To] // fornow: assume only one spring
 		} else {
 			d = nil
 		}
@@ -483,8 +523,8 @@ func (a *anim) worldCycle() {
 			a.platformCycle(i)
 		}
 	}
-	for i := a.iLetterDots; i < a.nDots; i++ {
-		if a.dots[i].X < a.viewX-a.width*.5 || a.dots[i].Y > a.height*1.5 {
+	for i := a.iLetterDots; i < len(a.web.Nodes); i++ {
+		if a.web.Nodes[i].X < a.viewX-a.width*.5 || a.web.Nodes[i].Y > a.height*1.5 {
 			a.alienCycle(i)
 		}
 	}
@@ -493,16 +533,16 @@ func (a *anim) worldCycle() {
 func (a *anim) appendAliens() {
 	nAliens := 5
 	nBody := 2 // minimum: 1
-	n := a.nDots + nAliens*(1+nBody)
-	if n >= len(a.dots) {
+	n := len(a.web.Nodes) + nAliens*(1+nBody)
+	if n >= cap(a.web.Nodes) {
 		return // overflow: skip aliens
 	}
 	a.iLetterDots = n - nAliens
-	for q := a.nDots; q < n; q++ {
+	for q := len(a.web.Nodes); q < n; q++ {
 		a.newDot(0, 0)
 	}
 	j := a.iLetterDots
-	i := a.nDots
+	i := len(a.web.Nodes)
 	for i > a.iLetterDots {
 		i--
 		j--
@@ -516,16 +556,73 @@ func (a *anim) appendAliens() {
 }
 
 func (a *anim) start() {
-	springweb.StepsPrepare(a.dots[:a.nDots])
+	springweb.StepsPrepare(a.web.Nodes)
 	a.lastCall = time.Now()
-	a.nCarDots = a.nDots
+	a.nCarDots = len(a.web.Nodes)
 	a.appendAliens()
-	js.Global().Call("requestAnimationFrame", a.callback)
+	w := springweb.NewWorld(a.web.Nodes)
+	w.Fields = []springweb.ForceField{springweb.FieldFunc(a.gravityField)}
+	a.runner = springweb.NewRunner(w, stepDuration, maxSubsteps)
+	a.runner.OnStep = a.substep
+	a.recording, _ = springweb.NewRecording(a.runner, a.seed)
+	a.recording.Program = "springweb-game"
+	if !a.looping {
+		a.looping = true
+		js.Global().Call("requestAnimationFrame", a.callback)
+	}
+}
+
+func (a *anim) begin(seed int64) {
+	a.seed = seed
+	a.rands = rand.New(rand.NewSource(seed))
+	a.web.Nodes = a.web.Nodes[:0]
+	a.viewX = 0
+	a.wheelForce = 0
+	a.wheels = make([]wheel, a.nWheels)
+	a.platforms = make([]platform, a.nPlatforms)
+	a.alienLetters = make([]int, a.nLetterAliens)
+	a.haveLetters = make([]bool, 26)
+
+	h := 2 * a.dotSize
+	a.newDot(h, a.height-h)
+	a.newDot(h*3, a.height-h)
+	a.newDotM(h*(1+a.vary()), a.height-h*1.5, minMass)
+	a.newLine(1, 0)
+	a.newLineK(2, 1, minK)
+	a.newLineK(2, 0, minK)
+	a.start()
 }
 
 func (a *anim) pointerMove(event js.Value) {
 	x := event.Get("clientX").Float()
-	a.wheelForce = (2*x/a.width - 1) * maxWheelForce
+	a.input(springweb.Event{Kind: springweb.EventForce,
+		Value: (2*x/a.width - 1) * maxWheelForce})
+}
+
+func (a *anim) recordingJSON() string {
+	if a.recording == nil {
+		return ""
+	}
+	data, err := json.Marshal(a.recording)
+	if err != nil {
+		log(err.Error())
+		return ""
+	}
+	return string(data)
+}
+
+func (a *anim) replay(data string) {
+	var rec springweb.Recording
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		log(err.Error())
+		return
+	}
+	a.begin(rec.Seed)
+	a.recording = nil
+	a.frames = rec.Frames
+	if len(a.frames) == 0 {
+		a.frames = nil
+	}
 }
 
 func main() {
@@ -538,14 +635,16 @@ func main() {
 			return nil
 		}))
 
-	h := 2 * a.dotSize
-	a.newDot(h, a.height-h)
-	a.newDot(h*3, a.height-h)
-	a.newDotM(h*(1+a.vary()), a.height-h*1.5, minMass)
-	a.newLine(1, 0)
-	a.newLineK(2, 1, minK)
-	a.newLineK(2, 0, minK)
-	a.start()
+	a.begin(time.Now().UnixNano())
+	js.Global().Set("springwebRecording",
+		js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return a.recordingJSON()
+		}))
+	js.Global().Set("springwebReplay",
+		js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			a.replay(args[0].String())
+			return nil
+		}))
 
 	<-make(chan bool)
 }
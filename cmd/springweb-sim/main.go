@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/biotty/springweb"
+)
+
+type link struct {
+	from, to int
+}
+
+type recorder interface {
+	header(w *springweb.World, links []link) error
+	record(w *springweb.World, links []link) error
+}
+
+func tensions(w *springweb.World, links []link) []float64 {
+	t := make([]float64, len(links))
+	k := 0
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		for _, s := range n.Springs {
+			for k < len(links) && (links[k].from != i || links[k].to != s.To) {
+				t[k] = math.NaN()
+				k++
+			}
+			if k == len(links) {
+				break
+			}
+			to := &w.Nodes[s.To]
+			t[k] = s.K * (math.Hypot(to.X-n.X, to.Y-n.Y) - s.Distance)
+			k++
+		}
+	}
+	for ; k < len(links); k++ {
+		t[k] = math.NaN()
+	}
+	return t
+}
+
+type csvRecorder struct {
+	out *bufio.Writer
+}
+
+func (r *csvRecorder) header(w *springweb.World, links []link) error {
+	cols := []string{"time"}
+	for i := range w.Nodes {
+		cols = append(cols, fmt.Sprintf("x%d", i), fmt.Sprintf("y%d", i))
+	}
+	for _, l := range links {
+		cols = append(cols, fmt.Sprintf("tension%d_%d", l.from, l.to))
+	}
+	cols = append(cols, "kinetic", "spring", "arm", "gravity", "dissipated", "total")
+	_, err := fmt.Fprintln(r.out, strings.Join(cols, ","))
+	return err
+}
+
+func formatFloat(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (r *csvRecorder) record(w *springweb.World, links []link) error {
+	cols := []string{formatFloat(w.Time)}
+	for i := range w.Nodes {
+		cols = append(cols, formatFloat(w.Nodes[i].X), formatFloat(w.Nodes[i].Y))
+	}
+	for _, t := range tensions(w, links) {
+		cols = append(cols, formatFloat(t))
+	}
+	e := w.Energy()
+	for _, v := range []float64{e.Kinetic, e.Spring, e.Arm, e.Gravity, e.Dissipated, e.Total()} {
+		cols = append(cols, formatFloat(v))
+	}
+	_, err := fmt.Fprintln(r.out, strings.Join(cols, ","))
+	return err
+}
+
+type ndjsonRecorder struct {
+	enc *json.Encoder
+}
+
+type nodeRecord struct {
+	X, Y, VelocityX, VelocityY float64
+}
+
+type springRecord struct {
+	From, To int
+	Tension  float64
+}
+
+type stepRecord struct {
+	Time    float64
+	Nodes   []nodeRecord
+	Springs []springRecord
+	Energy  springweb.Energy
+	Total   float64
+}
+
+func (r *ndjsonRecorder) header(w *springweb.World, links []link) error {
+	return nil
+}
+
+func (r *ndjsonRecorder) record(w *springweb.World, links []link) error {
+	rec := stepRecord{Time: w.Time, Energy: w.Energy()}
+	rec.Total = rec.Energy.Total()
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		rec.Nodes = append(rec.Nodes, nodeRecord{n.X, n.Y, n.VelocityX, n.VelocityY})
+	}
+	for k, t := range tensions(w, links) {
+		if !math.IsNaN(t) {
+			rec.Springs = append(rec.Springs, springRecord{links[k].from, links[k].to, t})
+		}
+	}
+	return r.enc.Encode(&rec)
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("%q: want %d comma separated numbers", s, n)
+	}
+	v := make([]float64, n)
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = f
+	}
+	return v, nil
+}
+
+func integrator(name string, tolerance float64) (springweb.Integrator, error) {
+	switch name {
+	case "euler":
+		return springweb.SymplecticEuler{}, nil
+	case "verlet":
+		return springweb.VelocityVerlet{}, nil
+	case "rk4":
+		return &springweb.RK4{}, nil
+	case "implicit":
+		return &springweb.Implicit{}, nil
+	case "adaptive":
+		return &springweb.Adaptive{Tolerance: tolerance}, nil
+	}
+	return nil, fmt.Errorf("unknown integrator %q", name)
+}
+
+func run() error {
+	dt := flag.Float64("dt", 1./120, "step duration")
+	duration := flag.Float64("duration", 10, "simulated time")
+	every := flag.Int("every", 1, "record every n-th step")
+	format := flag.String("format", "csv", "output format: csv or ndjson")
+	output := flag.String("o", "", "output file (default stdout)")
+	integratorName := flag.String("integrator", "euler", "integrator: euler, verlet, rk4, implicit or adaptive")
+	tolerance := flag.Float64("tolerance", 1e-3, "position error per step of the adaptive integrator")
+	gravity := flag.String("gravity", "", "gravity field x,y")
+	drag := flag.Float64("drag", 0, "drag coefficient")
+	wind := flag.String("wind", "", "wind field x,y,coefficient")
+	bounds := flag.String("bounds", "", "bounds left,top,right,bottom,bounce")
+	collisions := flag.Bool("collisions", false, "collide all nodes")
+	continuous := flag.Bool("continuous", false, "sweep fast nodes so that they do not pass through others")
+	velocityCap := flag.Float64("cap", springweb.VelocityCap, "largest move per step as a fraction of the node radius, 0 for none")
+	settle := flag.Bool("settle", false, "start from the static equilibrium")
+	workers := flag.Int("workers", 1, "goroutines evaluating forces")
+	replay := flag.Bool("replay", false, "read a recording instead of a web and replay its frames")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: springweb-sim [flags] web.json\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       springweb-sim -replay [flags] recording.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *dt <= 0 || *every <= 0 {
+		return fmt.Errorf("dt and every must be positive")
+	}
+	if *replay && (set["dt"] || set["duration"] || set["settle"]) {
+		return fmt.Errorf("-dt, -duration and -settle do not apply to -replay, which follows the recorded frames")
+	}
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	var w *springweb.World
+	var rec springweb.Recording
+	var runner *springweb.Runner
+	if *replay {
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		if err := headless(&rec); err != nil {
+			return err
+		}
+		w = springweb.NewWorld(nil)
+		if runner, err = rec.Runner(w); err != nil {
+			return err
+		}
+	} else if w, err = springweb.Unmarshal(data); err != nil {
+		return err
+	}
+	if w.Integrator, err = integrator(*integratorName, *tolerance); err != nil {
+		return err
+	}
+	step := *dt
+	if runner != nil {
+		step = runner.Duration
+	}
+	if stable := w.StableDuration(); step > stable && *integratorName != "implicit" && *integratorName != "adaptive" {
+		fmt.Fprintf(os.Stderr, "springweb-sim: step %g exceeds the stable step %g\n", step, stable)
+	}
+	if *gravity != "" {
+		v, err := parseFloats(*gravity, 2)
+		if err != nil {
+			return err
+		}
+		w.GravityX, w.GravityY = v[0], v[1]
+	}
+	if *drag != 0 {
+		w.Fields = append(w.Fields, springweb.Drag{Coefficient: *drag})
+	}
+	if *wind != "" {
+		v, err := parseFloats(*wind, 3)
+		if err != nil {
+			return err
+		}
+		w.Fields = append(w.Fields, springweb.Wind{X: v[0], Y: v[1], Coefficient: v[2]})
+	}
+	if *bounds != "" {
+		v, err := parseFloats(*bounds, 5)
+		if err != nil {
+			return err
+		}
+		w.Bounds = &springweb.Bounds{Left: v[0], Top: v[1], Right: v[2], Bottom: v[3], Bounce: v[4]}
+	}
+	w.Workers = *workers
+	if set["cap"] {
+		w.VelocityCap = *velocityCap
+	}
+	if *collisions {
+		w.Collisions = &springweb.Collisions{Continuous: *continuous}
+	}
+	caps := 0
+	w.OnCap = func(int) { caps++ }
+	defer func() {
+		if caps > 0 {
+			fmt.Fprintf(os.Stderr, "springweb-sim: velocity capped %d times\n", caps)
+		}
+	}()
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	buf := bufio.NewWriter(out)
+	defer buf.Flush()
+	var r recorder
+	switch *format {
+	case "csv":
+		r = &csvRecorder{buf}
+	case "ndjson":
+		r = &ndjsonRecorder{json.NewEncoder(buf)}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var links []link
+	for i := range w.Nodes {
+		for _, s := range w.Nodes[i].Springs {
+			links = append(links, link{i, s.To})
+		}
+	}
+	if err := r.header(w, links); err != nil {
+		return err
+	}
+	if runner != nil {
+		return replayFrames(runner, rec.Frames, r, links, *every)
+	}
+	w.Prepare()
+	if *settle {
+		eq := w.Equilibrium(1e-9, 100000)
+		if !eq.Converged {
+			fmt.Fprintf(os.Stderr, "springweb-sim: equilibrium residual %g after %d iterations\n",
+				eq.Residual, eq.Iterations)
+		}
+		if err := eq.Apply(w); err != nil {
+			return err
+		}
+	}
+	if err := r.record(w, links); err != nil {
+		return err
+	}
+	steps := int(math.Round(*duration / *dt))
+	for n := 1; n <= steps; n++ {
+		w.Step(*dt)
+		if n%*every == 0 {
+			if err := r.record(w, links); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func headless(rec *springweb.Recording) error {
+	if rec.Program == "springweb-game" {
+		return fmt.Errorf("a springweb-game recording replays only in the game")
+	}
+	for _, f := range rec.Frames {
+		for _, e := range f.Events {
+			if e.Kind == springweb.EventForce {
+				return fmt.Errorf("the recording applies forces, which replay only in the program that recorded them")
+			}
+		}
+	}
+	return nil
+}
+
+func replayFrames(runner *springweb.Runner, frames []springweb.Frame, r recorder, links []link, every int) error {
+	w := runner.World
+	if err := r.record(w, links); err != nil {
+		return err
+	}
+	var grab springweb.Grab
+	var err error
+	n := 0
+	runner.OnStep = func(duration float64) {
+		grab.Step(w, duration)
+		n++
+		if n%every == 0 && err == nil {
+			err = r.record(w, links)
+		}
+	}
+	for _, f := range frames {
+		for _, e := range f.Events {
+			grab.Apply(w, e)
+		}
+		runner.Advance(f.Elapsed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "springweb-sim:", err)
+		os.Exit(1)
+	}
+}
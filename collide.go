@@ -0,0 +1,481 @@
+package springweb
+
+import (
+	"math"
+	"sort"
+)
+
+type cell struct {
+	x, y int
+}
+
+type segment struct {
+	i, j int
+}
+
+type hit struct {
+	t       float64
+	i, j, k int
+}
+
+type Collisions struct {
+	Restitution     float64
+	CellSize        float64
+	Segments        bool
+	SpringThickness float64
+	Continuous      bool
+	cells           map[cell][]int
+	segmentCells    map[cell][]segment
+	joined          map[[2]int]bool
+	extents         map[cell]float64
+	hits            []hit
+	fast            []int
+	handled         []bool
+}
+
+func (c *Collisions) settings() *Collisions {
+	settings := *c
+	settings.cells, settings.segmentCells, settings.joined, settings.extents = nil, nil, nil, nil
+	settings.hits, settings.fast, settings.handled = nil, nil, nil
+	return &settings
+}
+
+func (c *Collisions) cellOf(node *Node, size float64) cell {
+	return cell{int(math.Floor(node.X / size)), int(math.Floor(node.Y / size))}
+}
+
+func (c *Collisions) prepare(w *World) float64 {
+	size := c.CellSize
+	if size <= 0 {
+		for i := range w.Nodes {
+			size = math.Max(size, w.Nodes[i].R*2)
+		}
+	}
+	if c.cells == nil {
+		c.cells = make(map[cell][]int)
+		c.segmentCells = make(map[cell][]segment)
+		c.joined = make(map[[2]int]bool)
+		c.extents = make(map[cell]float64)
+	}
+	for k, v := range c.segmentCells {
+		if len(v) == 0 {
+			delete(c.segmentCells, k)
+		} else {
+			c.segmentCells[k] = v[:0]
+		}
+	}
+	for k, v := range c.cells {
+		if len(v) == 0 {
+			delete(c.cells, k)
+		} else {
+			c.cells[k] = v[:0]
+		}
+	}
+	for k := range c.joined {
+		delete(c.joined, k)
+	}
+	for k := range c.extents {
+		delete(c.extents, k)
+	}
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		for _, s := range n.Springs {
+			j := s.To
+			c.joined[[2]int{i, j}] = true
+			c.joined[[2]int{j, i}] = true
+		}
+		k := c.cellOf(n, size)
+		c.cells[k] = append(c.cells[k], i)
+		c.extend(k, n.R)
+	}
+	return size
+}
+
+func (c *Collisions) prepareSegments(w *World, size float64) {
+	maxR := 0.
+	for i := range w.Nodes {
+		maxR = math.Max(maxR, w.Nodes[i].R)
+	}
+	reach := maxR + c.SpringThickness*.5
+	for i := range w.Nodes {
+		a := &w.Nodes[i]
+		for _, s := range a.Springs {
+			b := &w.Nodes[s.To]
+			low := cell{int(math.Floor((math.Min(a.X, b.X) - reach) / size)),
+				int(math.Floor((math.Min(a.Y, b.Y) - reach) / size))}
+			high := cell{int(math.Floor((math.Max(a.X, b.X) + reach) / size)),
+				int(math.Floor((math.Max(a.Y, b.Y) + reach) / size))}
+			for x := low.x; x <= high.x; x++ {
+				for y := low.y; y <= high.y; y++ {
+					k := cell{x, y}
+					c.segmentCells[k] = append(c.segmentCells[k], segment{i, s.To})
+					if c.SpringThickness > 0 {
+						c.extend(k, c.SpringThickness*.5)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c *Collisions) extend(k cell, extent float64) {
+	if e, ok := c.extents[k]; !ok || extent < e {
+		c.extents[k] = extent
+	}
+}
+
+func (c *Collisions) reach(n *Node, size float64) float64 {
+	reach := n.R
+	start := cell{int(math.Floor(n.stepX / size)), int(math.Floor(n.stepY / size))}
+	for _, k := range [2]cell{start, c.cellOf(n, size)} {
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if e, ok := c.extents[cell{k.x + dx, k.y + dy}]; ok {
+					reach = math.Min(reach, e)
+				}
+			}
+		}
+	}
+	return reach
+}
+
+func (c *Collisions) step(w *World, duration float64) {
+	nodes := w.Nodes
+	if len(nodes) == 0 {
+		return
+	}
+	size := c.prepare(w)
+	if c.Continuous {
+		if c.Segments {
+			c.prepareSegments(w, size)
+		}
+		if c.sweep(w, size, duration) {
+			size = c.prepare(w)
+		}
+	}
+	for i := range nodes {
+		k := c.cellOf(&nodes[i], size)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range c.cells[cell{k.x + dx, k.y + dy}] {
+					if j <= i || c.joined[[2]int{i, j}] {
+						continue
+					}
+					c.resolve(&nodes[i], &nodes[j])
+				}
+			}
+		}
+	}
+	if !c.Segments {
+		return
+	}
+	c.prepareSegments(w, size)
+	for k := range nodes {
+		for _, g := range c.segmentCells[c.cellOf(&nodes[k], size)] {
+			if k == g.i || k == g.j || c.joined[[2]int{k, g.i}] || c.joined[[2]int{k, g.j}] {
+				continue
+			}
+			c.resolveSegment(&nodes[k], &nodes[g.i], &nodes[g.j])
+		}
+	}
+}
+
+func (c *Collisions) sweep(w *World, size, duration float64) bool {
+	nodes := w.Nodes
+	c.hits = c.hits[:0]
+	c.fast = c.fast[:0]
+	for i := range nodes {
+		a := &nodes[i]
+		if a.fixed() {
+			continue
+		}
+		if move := distanceXY(a.X-a.stepX, a.Y-a.stepY); move == 0 || move <= c.reach(a, size) {
+			continue
+		}
+		c.fast = append(c.fast, i)
+		low := cell{int(math.Floor(math.Min(a.X, a.stepX)/size)) - 1,
+			int(math.Floor(math.Min(a.Y, a.stepY)/size)) - 1}
+		high := cell{int(math.Floor(math.Max(a.X, a.stepX)/size)) + 1,
+			int(math.Floor(math.Max(a.Y, a.stepY)/size)) + 1}
+		for x := low.x; x <= high.x; x++ {
+			for y := low.y; y <= high.y; y++ {
+				k := cell{x, y}
+				for _, j := range c.cells[k] {
+					if j == i || c.joined[[2]int{i, j}] {
+						continue
+					}
+					if t, ok := sweepNodes(a, &nodes[j]); ok {
+						c.hits = append(c.hits, hit{t, i, j, -1})
+					}
+				}
+				for _, g := range c.segmentCells[k] {
+					if i == g.i || i == g.j || c.joined[[2]int{i, g.i}] || c.joined[[2]int{i, g.j}] {
+						continue
+					}
+					if t, ok := c.sweepSegment(a, &nodes[g.i], &nodes[g.j]); ok {
+						c.hits = append(c.hits, hit{t, i, g.i, g.j})
+					}
+				}
+			}
+		}
+	}
+	for m, i := range c.fast {
+		for _, j := range c.fast[m+1:] {
+			if c.joined[[2]int{i, j}] {
+				continue
+			}
+			if t, ok := sweepNodes(&nodes[i], &nodes[j]); ok {
+				c.hits = append(c.hits, hit{t, i, j, -1})
+			}
+		}
+	}
+	if len(c.hits) == 0 {
+		return false
+	}
+	sort.SliceStable(c.hits, func(m, n int) bool { return c.hits[m].t < c.hits[n].t })
+	if cap(c.handled) < len(nodes) {
+		c.handled = make([]bool, len(nodes))
+	}
+	c.handled = c.handled[:len(nodes)]
+	for i := range c.handled {
+		c.handled[i] = false
+	}
+	for _, h := range c.hits {
+		if c.handled[h.i] || c.handled[h.j] || h.k >= 0 && c.handled[h.k] {
+			continue
+		}
+		c.handled[h.i], c.handled[h.j] = true, true
+		n, a := &nodes[h.i], &nodes[h.j]
+		rewind(n, h.t)
+		rewind(a, h.t)
+		if h.k < 0 {
+			xDiff, yDiff := a.X-n.X, a.Y-n.Y
+			d := distanceXY(xDiff, yDiff)
+			if d != 0 {
+				c.bounce(n, a, xDiff/d, yDiff/d)
+			}
+		} else {
+			c.handled[h.k] = true
+			b := &nodes[h.k]
+			rewind(b, h.t)
+			t, xDiff, yDiff := closest(n, a, b)
+			d := distanceXY(xDiff, yDiff)
+			if d != 0 {
+				c.bounceSegment(n, a, b, t, xDiff/d, yDiff/d)
+			}
+			advance(b, (1-h.t)*duration)
+		}
+		advance(n, (1-h.t)*duration)
+		advance(a, (1-h.t)*duration)
+	}
+	return true
+}
+
+func rewind(n *Node, t float64) {
+	if !n.fixed() {
+		n.X = n.stepX + t*(n.X-n.stepX)
+		n.Y = n.stepY + t*(n.Y-n.stepY)
+	}
+}
+
+func advance(n *Node, duration float64) {
+	if !n.fixed() {
+		n.X += n.VelocityX * duration
+		n.Y += n.VelocityY * duration
+	}
+}
+
+func sweepNodes(a, b *Node) (t float64, ok bool) {
+	r := a.R + b.R
+	xDiff, yDiff := b.stepX-a.stepX, b.stepY-a.stepY
+	vX := (b.X - b.stepX) - (a.X - a.stepX)
+	vY := (b.Y - b.stepY) - (a.Y - a.stepY)
+	qA := vX*vX + vY*vY
+	qB := 2 * (xDiff*vX + yDiff*vY)
+	qC := xDiff*xDiff + yDiff*yDiff - r*r
+	if qC <= 0 || qB >= 0 || qA == 0 {
+		return 0, false
+	}
+	disc := qB*qB - 4*qA*qC
+	if disc < 0 {
+		return 0, false
+	}
+	t = (-qB - math.Sqrt(disc)) / (2 * qA)
+	return t, t <= 1
+}
+
+func side(nX, nY, aX, aY, bX, bY float64) float64 {
+	segX, segY := bX-aX, bY-aY
+	length := distanceXY(segX, segY)
+	if length == 0 {
+		return 0
+	}
+	return (segX*(nY-aY) - segY*(nX-aX)) / length
+}
+
+func (c *Collisions) sweepSegment(n, a, b *Node) (t float64, ok bool) {
+	r := n.R + c.SpringThickness*.5
+	d0 := side(n.stepX, n.stepY, a.stepX, a.stepY, b.stepX, b.stepY)
+	d1 := side(n.X, n.Y, a.X, a.Y, b.X, b.Y)
+	switch {
+	case d0 >= r && d1 < r:
+		t = (d0 - r) / (d0 - d1)
+	case d0 <= -r && d1 > -r:
+		t = (-r - d0) / (d1 - d0)
+	default:
+		return 0, false
+	}
+	at := func(n *Node) (x, y float64) {
+		return n.stepX + t*(n.X-n.stepX), n.stepY + t*(n.Y-n.stepY)
+	}
+	nX, nY := at(n)
+	aX, aY := at(a)
+	bX, bY := at(b)
+	segX, segY := bX-aX, bY-aY
+	length2 := segX*segX + segY*segY
+	if length2 == 0 {
+		return 0, false
+	}
+	u := ((nX-aX)*segX + (nY-aY)*segY) / length2
+	return t, u >= 0 && u <= 1
+}
+
+func closest(n, a, b *Node) (t, xDiff, yDiff float64) {
+	segX := b.X - a.X
+	segY := b.Y - a.Y
+	if length2 := segX*segX + segY*segY; length2 != 0 {
+		t = ((n.X-a.X)*segX + (n.Y-a.Y)*segY) / length2
+		t = math.Max(0, math.Min(1, t))
+	}
+	return t, n.X - (a.X + t*segX), n.Y - (a.Y + t*segY)
+}
+
+func (c *Collisions) resolve(a, b *Node) {
+	xDiff := b.X - a.X
+	yDiff := b.Y - a.Y
+	d := distanceXY(xDiff, yDiff)
+	depth := a.R + b.R - d
+	if depth <= 0 || d == 0 {
+		return
+	}
+	xDiffN := xDiff / d
+	yDiffN := yDiff / d
+	wA, wB := a.inverseMass(), b.inverseMass()
+	wSum := wA + wB
+	if wSum == 0 {
+		return
+	}
+	a.X -= xDiffN * depth * wA / wSum
+	a.Y -= yDiffN * depth * wA / wSum
+	b.X += xDiffN * depth * wB / wSum
+	b.Y += yDiffN * depth * wB / wSum
+	c.bounce(a, b, xDiffN, yDiffN)
+}
+
+func (c *Collisions) bounce(a, b *Node, xDiffN, yDiffN float64) {
+	wA, wB := a.inverseMass(), b.inverseMass()
+	wSum := wA + wB
+	approach := (b.VelocityX-a.VelocityX)*xDiffN + (b.VelocityY-a.VelocityY)*yDiffN
+	if approach >= 0 || wSum == 0 {
+		return
+	}
+	m := mix(a.Material, b.Material, Material{Restitution: c.Restitution})
+	impulse := -(1 + m.Restitution) * approach / wSum
+	a.VelocityX -= impulse * xDiffN * wA
+	a.VelocityY -= impulse * yDiffN * wA
+	b.VelocityX += impulse * xDiffN * wB
+	b.VelocityY += impulse * yDiffN * wB
+	if m.StaticFriction == 0 && m.KineticFriction == 0 {
+		return
+	}
+	vX, vY := b.VelocityX-a.VelocityX, b.VelocityY-a.VelocityY
+	normal := vX*xDiffN + vY*yDiffN
+	slipX, slipY := vX-normal*xDiffN, vY-normal*yDiffN
+	if slip := distanceXY(slipX, slipY); slip > 0 {
+		f := m.friction(slip/wSum, impulse) / slip
+		a.VelocityX += slipX * f * wA
+		a.VelocityY += slipY * f * wA
+		b.VelocityX -= slipX * f * wB
+		b.VelocityY -= slipY * f * wB
+	}
+}
+
+func (c *Collisions) resolveSegment(n, a, b *Node) {
+	segX := b.X - a.X
+	segY := b.Y - a.Y
+	length2 := segX*segX + segY*segY
+	if length2 == 0 {
+		return
+	}
+	t := ((n.X-a.X)*segX + (n.Y-a.Y)*segY) / length2
+	t = math.Max(0, math.Min(1, t))
+	pX := a.X + t*segX
+	pY := a.Y + t*segY
+	xDiff := n.X - pX
+	yDiff := n.Y - pY
+	d := distanceXY(xDiff, yDiff)
+	depth := n.R + c.SpringThickness*.5 - d
+	if depth <= 0 || d == 0 {
+		return
+	}
+	xDiffN := xDiff / d
+	yDiffN := yDiff / d
+	wN, wA, wB := n.inverseMass(), (1-t)*a.inverseMass(), t*b.inverseMass()
+	wSum := wN + (1-t)*wA + t*wB
+	if wSum == 0 {
+		return
+	}
+	n.X += xDiffN * depth * wN / wSum
+	n.Y += yDiffN * depth * wN / wSum
+	a.X -= xDiffN * depth * wA / wSum
+	a.Y -= yDiffN * depth * wA / wSum
+	b.X -= xDiffN * depth * wB / wSum
+	b.Y -= yDiffN * depth * wB / wSum
+	c.bounceSegment(n, a, b, t, xDiffN, yDiffN)
+}
+
+func (c *Collisions) bounceSegment(n, a, b *Node, t, xDiffN, yDiffN float64) {
+	wN, wA, wB := n.inverseMass(), (1-t)*a.inverseMass(), t*b.inverseMass()
+	wSum := wN + (1-t)*wA + t*wB
+	vX := n.VelocityX - (1-t)*a.VelocityX - t*b.VelocityX
+	vY := n.VelocityY - (1-t)*a.VelocityY - t*b.VelocityY
+	approach := vX*xDiffN + vY*yDiffN
+	if approach >= 0 || wSum == 0 {
+		return
+	}
+	m := mix(n.Material, springMaterial(a, b), Material{Restitution: c.Restitution})
+	impulse := -(1 + m.Restitution) * approach / wSum
+	n.VelocityX += impulse * xDiffN * wN
+	n.VelocityY += impulse * yDiffN * wN
+	a.VelocityX -= impulse * xDiffN * wA
+	a.VelocityY -= impulse * yDiffN * wA
+	b.VelocityX -= impulse * xDiffN * wB
+	b.VelocityY -= impulse * yDiffN * wB
+	if m.StaticFriction == 0 && m.KineticFriction == 0 {
+		return
+	}
+	vX = n.VelocityX - (1-t)*a.VelocityX - t*b.VelocityX
+	vY = n.VelocityY - (1-t)*a.VelocityY - t*b.VelocityY
+	normal := vX*xDiffN + vY*yDiffN
+	slipX, slipY := vX-normal*xDiffN, vY-normal*yDiffN
+	if slip := distanceXY(slipX, slipY); slip > 0 {
+		f := m.friction(slip/wSum, impulse) / slip
+		n.VelocityX -= slipX * f * wN
+		n.VelocityY -= slipY * f * wN
+		a.VelocityX += slipX * f * wA
+		a.VelocityY += slipY * f * wA
+		b.VelocityX += slipX * f * wB
+		b.VelocityY += slipY * f * wB
+	}
+}
+
+func springMaterial(a, b *Node) *Material {
+	switch {
+	case a.Material == nil:
+		return b.Material
+	case b.Material == nil:
+		return a.Material
+	}
+	m := mix(a.Material, b.Material, Material{})
+	return &m
+}
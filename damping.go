@@ -0,0 +1,94 @@
+package springweb
+
+type DampingMode int
+
+const (
+	CoulombDamping DampingMode = iota
+	ViscousDamping
+)
+
+func (s *Spring) dampForce(node, to *Node) (forceX, forceY float64) {
+	xDiff := to.X - node.X
+	yDiff := to.Y - node.Y
+	d := distanceXY(xDiff, yDiff)
+	xDiffN := xDiff / d
+	yDiffN := yDiff / d
+	rate := (to.VelocityX-node.VelocityX)*xDiffN + (to.VelocityY-node.VelocityY)*yDiffN
+	forceX = xDiffN * s.Damping * rate
+	forceY = yDiffN * s.Damping * rate
+	return
+}
+
+func (s *Spring) damp(node, to *Node) {
+	forceX, forceY := s.dampForce(node, to)
+	node.push(forceX, forceY)
+	to.push(-forceX, -forceY)
+	node.dampArm(&s.FromArm, to)
+	to.dampArm(&s.ToArm, node)
+}
+
+func (node *Node) armRate(to *Node) float64 {
+	xDiff := to.X - node.X
+	yDiff := to.Y - node.Y
+	return (xDiff*(to.VelocityY-node.VelocityY) - yDiff*(to.VelocityX-node.VelocityX)) / (xDiff*xDiff + yDiff*yDiff)
+}
+
+func (arm *Arm) turn(node, to *Node, duration float64) float64 {
+	return (arm.angleAt(node.angle(to)) - arm.Angle()) / duration
+}
+
+func spin(nodes []Node, rate func(arm *Arm, node, to *Node) float64) {
+	for i := range nodes {
+		nodes[i].spin = 0
+		nodes[i].spinSum = 0
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			t := &nodes[s.To]
+			n.spin += s.FromArm.Damping * rate(&s.FromArm, n, t)
+			t.spin += s.ToArm.Damping * rate(&s.ToArm, t, n)
+			n.spinSum += s.FromArm.Damping
+			t.spinSum += s.ToArm.Damping
+		}
+	}
+	for i := range nodes {
+		if n := &nodes[i]; n.spinSum != 0 {
+			n.spin /= n.spinSum
+		}
+	}
+}
+
+func armRates(nodes []Node) {
+	spin(nodes, func(arm *Arm, node, to *Node) float64 { return node.armRate(to) })
+}
+
+func (node *Node) dampArmForce(arm *Arm, to *Node) (forceX, forceY float64) {
+	xDiff := to.X - node.X
+	yDiff := to.Y - node.Y
+	d2 := xDiff*xDiff + yDiff*yDiff
+	f := arm.Damping * (node.armRate(to) - node.spin) / d2
+	forceX = yDiff * f
+	forceY = -xDiff * f
+	return
+}
+
+func (node *Node) dampArm(arm *Arm, to *Node) {
+	forceX, forceY := node.dampArmForce(arm, to)
+	node.push(-forceX, -forceY)
+	to.push(forceX, forceY)
+}
+
+func (arm *Arm) viscousDissipation(node, to *Node, duration float64) float64 {
+	rate := arm.turn(node, to, duration) - node.spin
+	return arm.Damping * rate * rate * duration
+}
+
+func (s *Spring) viscousDissipation(node, to *Node, duration float64) float64 {
+	distIncr := distance(node, to) - s.lastDistance
+	work := s.Damping * distIncr * distIncr / duration
+	work += s.FromArm.viscousDissipation(node, to, duration)
+	work += s.ToArm.viscousDissipation(to, node, duration)
+	return work
+}
@@ -0,0 +1,36 @@
+package springweb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpinKeepsAngularMomentum(t *testing.T) {
+	w := NewWorld(nil)
+	w.AddNode(NewNode(0, 0, 1, 1))
+	w.AddNode(NewNode(10, 0, 1, 1))
+	w.AddNode(NewNode(5, 8, 1, 1))
+	w.AddSpring(0, 1, 50, 20)
+	w.AddSpring(1, 2, 50, 20)
+	w.AddSpring(2, 0, 50, 20)
+	w.Prepare()
+	w.DampingMode = ViscousDamping
+	for i := range w.Nodes {
+		for j := range w.Nodes[i].Springs {
+			s := &w.Nodes[i].Springs[j]
+			s.Damping, s.FromArm.Damping, s.ToArm.Damping = 1, 5, 5
+		}
+	}
+	m := w.Momentum()
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		n.VelocityX, n.VelocityY = -(n.Y - m.CenterY), n.X-m.CenterX
+	}
+	start := w.Momentum().Angular
+	for step := 0; step < 200; step++ {
+		w.Step(.01)
+	}
+	if end := w.Momentum().Angular; math.Abs(end-start) > 1e-9*start {
+		t.Fatalf("angular momentum went from %g to %g", start, end)
+	}
+}
@@ -0,0 +1,77 @@
+package springweb
+
+import "math"
+
+type Energy struct {
+	Kinetic, Spring, Arm, Gravity, Dissipated float64
+}
+
+func (e Energy) Total() float64 {
+	return e.Kinetic + e.Spring + e.Arm + e.Gravity + e.Dissipated
+}
+
+type Momentum struct {
+	Mass, CenterX, CenterY float64
+	X, Y, Angular          float64
+}
+
+func (s *Spring) potential(node, to *Node) float64 {
+	d := distance(node, to)
+	stretch := d - s.Distance
+	e := .5 * s.K * stretch * stretch
+	impactDepth := (node.R + to.R) - d
+	if impactDepth > 0 {
+		refDepth := math.Min(node.R, to.R)
+		e += .5 * s.K * s.Distance * impactDepth * impactDepth / refDepth
+	}
+	return e
+}
+
+func (arm *Arm) potential(node, to *Node) float64 {
+	angleUnrest := arm.unrest(node, to)
+	return .5 * arm.K * angleUnrest * angleUnrest
+}
+
+func (w *World) Energy() Energy {
+	var e Energy
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		e.Kinetic += .5 * n.M * (n.VelocityX*n.VelocityX + n.VelocityY*n.VelocityY)
+		e.Gravity -= n.M * (w.GravityX*n.X + w.GravityY*n.Y)
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			to := &w.Nodes[s.To]
+			e.Spring += s.potential(n, to)
+			e.Arm += s.FromArm.potential(n, to)
+			e.Arm += s.ToArm.potential(to, n)
+		}
+	}
+	e.Dissipated = w.dissipated
+	return e
+}
+
+func MomentumOf(nodes []Node) Momentum {
+	var m Momentum
+	for i := range nodes {
+		n := &nodes[i]
+		m.Mass += n.M
+		m.CenterX += n.M * n.X
+		m.CenterY += n.M * n.Y
+		m.X += n.M * n.VelocityX
+		m.Y += n.M * n.VelocityY
+	}
+	if m.Mass == 0 {
+		return m
+	}
+	m.CenterX /= m.Mass
+	m.CenterY /= m.Mass
+	for i := range nodes {
+		n := &nodes[i]
+		m.Angular += n.M * ((n.X-m.CenterX)*n.VelocityY - (n.Y-m.CenterY)*n.VelocityX)
+	}
+	return m
+}
+
+func (w *World) Momentum() Momentum {
+	return MomentumOf(w.Nodes)
+}
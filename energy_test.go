@@ -0,0 +1,36 @@
+package springweb
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnergyAccounting(t *testing.T) {
+	for _, resist := range []float64{0, .05} {
+		w := randomWeb(60, 4)
+		for i := range w.Nodes {
+			for j := range w.Nodes[i].Springs {
+				s := &w.Nodes[i].Springs[j]
+				s.FromArm.K, s.ToArm.K = 0, 0
+			}
+		}
+		w.ArmResist, w.SpringResist = resist, resist
+		w.Integrator = VelocityVerlet{}
+		w.Nodes[5].VelocityX = 10
+		w.Nodes[30].VelocityY = -8
+		start := w.Energy().Total()
+		for step := 0; step < 2000; step++ {
+			w.Step(.001)
+			e := w.Energy()
+			if math.Abs(e.Total()-start) > 1e-4*math.Abs(start) {
+				t.Fatalf("resist %g step %d: total %g, started at %g", resist, step, e.Total(), start)
+			}
+			if resist == 0 && e.Dissipated != 0 {
+				t.Fatalf("step %d: %g dissipated without resistance", step, e.Dissipated)
+			}
+		}
+		if e := w.Energy(); resist > 0 && e.Dissipated <= 0 {
+			t.Fatalf("resist %g: nothing dissipated", resist)
+		}
+	}
+}
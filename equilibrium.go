@@ -0,0 +1,136 @@
+package springweb
+
+import (
+	"errors"
+	"math"
+)
+
+var errTopology = errors.New("springweb: web differs from the one solved for equilibrium")
+
+type Equilibrium struct {
+	X, Y       []float64
+	Residual   float64
+	Iterations int
+	Converged  bool
+	web        *Web
+}
+
+func (w *World) Equilibrium(tolerance float64, maxIterations int) *Equilibrium {
+	c := &World{Web: *w.Web.Clone(), GravityX: w.GravityX, GravityY: w.GravityY,
+		Fields: w.Fields, Time: w.Time}
+	nodes := c.Nodes
+	n := len(nodes)
+	vX, vY := make([]float64, n), make([]float64, n)
+	duration := 1e-3
+	if stable := w.StableDuration(); !math.IsInf(stable, 1) {
+		duration = .07 * stable
+	}
+	maxDuration := 10 * duration
+	alpha := .1
+	positive := 0
+	eq := &Equilibrium{X: make([]float64, n), Y: make([]float64, n), web: &c.Web}
+	for ; eq.Iterations < maxIterations; eq.Iterations++ {
+		for i := range nodes {
+			nodes[i].VelocityX, nodes[i].VelocityY = 0, 0
+			for j := range nodes[i].Springs {
+				s := &nodes[i].Springs[j]
+				s.weigh(&nodes[i], &nodes[s.To])
+			}
+		}
+		c.Forces()
+		power, vNorm, fNorm := 0., 0., 0.
+		eq.Residual = 0
+		for i := range nodes {
+			node := &nodes[i]
+			if node.fixed() {
+				continue
+			}
+			f := distanceXY(node.forceX, node.forceY)
+			eq.Residual = math.Max(eq.Residual, f)
+			power += node.forceX*vX[i] + node.forceY*vY[i]
+			vNorm += vX[i]*vX[i] + vY[i]*vY[i]
+			fNorm += f * f
+		}
+		if eq.Residual <= tolerance {
+			eq.Converged = true
+			break
+		}
+		if power > 0 {
+			mix := alpha * math.Sqrt(vNorm/fNorm)
+			for i := range nodes {
+				if !nodes[i].fixed() {
+					vX[i] = (1-alpha)*vX[i] + mix*nodes[i].forceX
+					vY[i] = (1-alpha)*vY[i] + mix*nodes[i].forceY
+				}
+			}
+			if positive++; positive > 5 {
+				duration = math.Min(duration*1.1, maxDuration)
+				alpha *= .99
+			}
+		} else {
+			for i := range nodes {
+				vX[i], vY[i] = 0, 0
+			}
+			duration *= .5
+			alpha = .1
+			positive = 0
+		}
+		for i := range nodes {
+			node := &nodes[i]
+			weight := duration * node.inverseMass()
+			vX[i] += node.forceX * weight
+			vY[i] += node.forceY * weight
+			dMove := duration * distanceXY(vX[i], vY[i])
+			if rMove := node.R * .1; dMove > rMove {
+				vX[i] *= rMove / dMove
+				vY[i] *= rMove / dMove
+			}
+			node.X += vX[i] * duration
+			node.Y += vY[i] * duration
+			node.avgRotationsPrepare()
+		}
+		avgRotations(nodes)
+	}
+	for i := range nodes {
+		eq.X[i], eq.Y[i] = nodes[i].X, nodes[i].Y
+	}
+	return eq
+}
+
+func (eq *Equilibrium) Apply(w *World) error {
+	if len(w.Nodes) != len(eq.web.Nodes) {
+		return errTopology
+	}
+	for i := range w.Nodes {
+		springs, settled := w.Nodes[i].Springs, eq.web.Nodes[i].Springs
+		if len(springs) != len(settled) {
+			return errTopology
+		}
+		for j := range springs {
+			if springs[j].To != settled[j].To {
+				return errTopology
+			}
+		}
+	}
+	for i := range w.Nodes {
+		n, settled := &w.Nodes[i], &eq.web.Nodes[i]
+		n.Place(settled.X, settled.Y)
+		n.VelocityX, n.VelocityY = 0, 0
+		n.Angle = settled.Angle
+	}
+	for i := range w.Nodes {
+		n, settled := &w.Nodes[i], &eq.web.Nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			to := &w.Nodes[s.To]
+			s.FromArm, s.ToArm = settled.Springs[j].FromArm, settled.Springs[j].ToArm
+			s.lastDistance = distance(n, to)
+			s.prevDistance = s.lastDistance
+			s.FromArm.lastAngleUnrest = s.FromArm.unrest(n, to)
+			s.FromArm.prevAngleUnrest = s.FromArm.lastAngleUnrest
+			s.ToArm.lastAngleUnrest = s.ToArm.unrest(to, n)
+			s.ToArm.prevAngleUnrest = s.ToArm.lastAngleUnrest
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package springweb
+
+import "math"
+
+type ForceField interface {
+	Force(node *Node, time float64) (x, y float64)
+}
+
+type FieldFunc func(node *Node, time float64) (x, y float64)
+
+func (f FieldFunc) Force(node *Node, time float64) (x, y float64) {
+	return f(node, time)
+}
+
+type Drag struct {
+	Coefficient float64
+}
+
+func (d Drag) Force(node *Node, time float64) (x, y float64) {
+	return -d.Coefficient * node.VelocityX, -d.Coefficient * node.VelocityY
+}
+
+type Wind struct {
+	X, Y, Coefficient float64
+}
+
+func (w Wind) Force(node *Node, time float64) (x, y float64) {
+	return w.Coefficient * (w.X - node.VelocityX), w.Coefficient * (w.Y - node.VelocityY)
+}
+
+type Attractor struct {
+	X, Y, Strength, Softening float64
+}
+
+func (a Attractor) Force(node *Node, time float64) (x, y float64) {
+	xDiff := a.X - node.X
+	yDiff := a.Y - node.Y
+	d2 := xDiff*xDiff + yDiff*yDiff + a.Softening*a.Softening
+	if d2 == 0 {
+		return 0, 0
+	}
+	f := a.Strength * node.M / (d2 * math.Sqrt(d2))
+	return xDiff * f, yDiff * f
+}
+
+type Vortex struct {
+	X, Y, Strength, Softening float64
+}
+
+func (v Vortex) Force(node *Node, time float64) (x, y float64) {
+	xDiff := node.X - v.X
+	yDiff := node.Y - v.Y
+	d2 := xDiff*xDiff + yDiff*yDiff + v.Softening*v.Softening
+	if d2 == 0 {
+		return 0, 0
+	}
+	f := v.Strength * node.M / d2
+	return -yDiff * f, xDiff * f
+}
+
+type Varying struct {
+	Field ForceField
+	Scale func(time float64) float64
+}
+
+func (v Varying) Force(node *Node, time float64) (x, y float64) {
+	x, y = v.Field.Force(node, time)
+	s := v.Scale(time)
+	return x * s, y * s
+}
+
+func Oscillating(amplitude, period, phase float64) func(float64) float64 {
+	return func(time float64) float64 {
+		return amplitude * math.Sin(2*math.Pi*time/period+phase)
+	}
+}
@@ -0,0 +1,191 @@
+package springweb
+
+import "math"
+
+type stiffness struct {
+	i, j       int
+	xx, xy, yy float64
+}
+
+type Implicit struct {
+	Tolerance     float64
+	MaxIterations int
+	Iterations    int
+	Residual      float64
+	blocks        []stiffness
+	rhsX, rhsY    []float64
+	dvX, dvY      []float64
+	rX, rY        []float64
+	zX, zY        []float64
+	pX, pY        []float64
+	apX, apY      []float64
+	diagX, diagY  []float64
+}
+
+func (im *Implicit) resize(n int) {
+	for _, buf := range []*[]float64{&im.rhsX, &im.rhsY, &im.dvX, &im.dvY,
+		&im.rX, &im.rY, &im.zX, &im.zY, &im.pX, &im.pY,
+		&im.apX, &im.apY, &im.diagX, &im.diagY} {
+		*buf = grow(*buf, n)
+	}
+}
+
+func (s *Spring) stiffness(node, to *Node) (xx, xy, yy float64) {
+	xDiff := to.X - node.X
+	yDiff := to.Y - node.Y
+	d := distanceXY(xDiff, yDiff)
+	ux, uy := xDiff/d, yDiff/d
+	kU := s.K
+	kT := math.Max(0, s.K*(1-s.Distance/d)) + (s.FromArm.K+s.ToArm.K)/(d*d)
+	if impactDepth := (node.R + to.R) - d; impactDepth > 0 {
+		kU += s.K * s.Distance / math.Min(node.R, to.R)
+	}
+	xx = kU*ux*ux + kT*uy*uy
+	xy = (kU - kT) * ux * uy
+	yy = kU*uy*uy + kT*ux*ux
+	return
+}
+
+func (im *Implicit) assemble(nodes []Node) {
+	im.blocks = im.blocks[:0]
+	for i := range nodes {
+		n := &nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			xx, xy, yy := s.stiffness(n, &nodes[s.To])
+			im.blocks = append(im.blocks, stiffness{i, s.To, xx, xy, yy})
+		}
+	}
+}
+
+func (im *Implicit) multiply(nodes []Node, h2 float64, vX, vY, outX, outY []float64) {
+	for i := range nodes {
+		if nodes[i].fixed() {
+			outX[i] = vX[i]
+			outY[i] = vY[i]
+		} else {
+			outX[i] = nodes[i].M * vX[i]
+			outY[i] = nodes[i].M * vY[i]
+		}
+	}
+	for _, b := range im.blocks {
+		a, c := &nodes[b.i], &nodes[b.j]
+		var dx, dy float64
+		if !a.fixed() {
+			dx, dy = vX[b.i], vY[b.i]
+		}
+		if !c.fixed() {
+			dx, dy = dx-vX[b.j], dy-vY[b.j]
+		}
+		fx := h2 * (b.xx*dx + b.xy*dy)
+		fy := h2 * (b.xy*dx + b.yy*dy)
+		if !a.fixed() {
+			outX[b.i] += fx
+			outY[b.i] += fy
+		}
+		if !c.fixed() {
+			outX[b.j] -= fx
+			outY[b.j] -= fy
+		}
+	}
+}
+
+func dot(aX, aY, bX, bY []float64) float64 {
+	sum := 0.
+	for i := range aX {
+		sum += aX[i]*bX[i] + aY[i]*bY[i]
+	}
+	return sum
+}
+
+func (im *Implicit) solve(nodes []Node, h2 float64) {
+	n := len(nodes)
+	for i := range nodes {
+		if nodes[i].fixed() {
+			im.diagX[i], im.diagY[i] = 1, 1
+			im.rhsX[i], im.rhsY[i] = 0, 0
+		} else {
+			im.diagX[i], im.diagY[i] = nodes[i].M, nodes[i].M
+		}
+	}
+	for _, b := range im.blocks {
+		if !nodes[b.i].fixed() {
+			im.diagX[b.i] += h2 * b.xx
+			im.diagY[b.i] += h2 * b.yy
+		}
+		if !nodes[b.j].fixed() {
+			im.diagX[b.j] += h2 * b.xx
+			im.diagY[b.j] += h2 * b.yy
+		}
+	}
+	tolerance := im.Tolerance
+	if tolerance == 0 {
+		tolerance = 1e-10
+	}
+	maxIterations := im.MaxIterations
+	if maxIterations == 0 {
+		maxIterations = 2*n + 10
+	}
+	for i := 0; i < n; i++ {
+		im.dvX[i], im.dvY[i] = 0, 0
+		im.rX[i], im.rY[i] = im.rhsX[i], im.rhsY[i]
+		im.zX[i], im.zY[i] = im.rX[i]/im.diagX[i], im.rY[i]/im.diagY[i]
+		im.pX[i], im.pY[i] = im.zX[i], im.zY[i]
+	}
+	limit := tolerance * tolerance * dot(im.rhsX, im.rhsY, im.rhsX, im.rhsY)
+	rz := dot(im.rX, im.rY, im.zX, im.zY)
+	rr := dot(im.rX, im.rY, im.rX, im.rY)
+	k := 0
+	for ; k < maxIterations && rr > limit; k++ {
+		im.multiply(nodes, h2, im.pX, im.pY, im.apX, im.apY)
+		alpha := rz / dot(im.pX, im.pY, im.apX, im.apY)
+		for i := 0; i < n; i++ {
+			im.dvX[i] += alpha * im.pX[i]
+			im.dvY[i] += alpha * im.pY[i]
+			im.rX[i] -= alpha * im.apX[i]
+			im.rY[i] -= alpha * im.apY[i]
+			im.zX[i] = im.rX[i] / im.diagX[i]
+			im.zY[i] = im.rY[i] / im.diagY[i]
+		}
+		rzNext := dot(im.rX, im.rY, im.zX, im.zY)
+		beta := rzNext / rz
+		rz = rzNext
+		for i := 0; i < n; i++ {
+			im.pX[i] = im.zX[i] + beta*im.pX[i]
+			im.pY[i] = im.zY[i] + beta*im.pY[i]
+		}
+		rr = dot(im.rX, im.rY, im.rX, im.rY)
+	}
+	im.Iterations = k
+	im.Residual = math.Sqrt(rr)
+}
+
+func (im *Implicit) Integrate(w *World, duration float64) {
+	nodes := w.Nodes
+	im.resize(len(nodes))
+	w.Forces()
+	im.assemble(nodes)
+	h2 := duration * duration
+	for i := range nodes {
+		im.rhsX[i] = duration * nodes[i].forceX
+		im.rhsY[i] = duration * nodes[i].forceY
+	}
+	for _, b := range im.blocks {
+		dx := nodes[b.i].VelocityX - nodes[b.j].VelocityX
+		dy := nodes[b.i].VelocityY - nodes[b.j].VelocityY
+		fx := h2 * (b.xx*dx + b.xy*dy)
+		fy := h2 * (b.xy*dx + b.yy*dy)
+		im.rhsX[b.i] -= fx
+		im.rhsY[b.i] -= fy
+		im.rhsX[b.j] += fx
+		im.rhsY[b.j] += fy
+	}
+	im.solve(nodes, h2)
+	for i := range nodes {
+		n := &nodes[i]
+		n.VelocityX += im.dvX[i]
+		n.VelocityY += im.dvY[i]
+		n.X += n.VelocityX * duration
+		n.Y += n.VelocityY * duration
+	}
+}
@@ -0,0 +1,111 @@
+package springweb
+
+type Integrator interface {
+	Integrate(w *World, duration float64)
+}
+
+type stepper interface {
+	Integrator
+	step(w *World, duration float64)
+}
+
+type SymplecticEuler struct{}
+
+func (SymplecticEuler) Integrate(w *World, duration float64) {
+	w.Forces()
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		n.accelerate(duration)
+		n.move(duration, w.VelocityCap)
+	}
+}
+
+type VelocityVerlet struct{}
+
+func (VelocityVerlet) Integrate(w *World, duration float64) {
+	half := duration * .5
+	w.Forces()
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		n.accelerate(half)
+		n.move(duration, w.VelocityCap)
+	}
+	time := w.Time
+	w.Time = time + duration
+	w.Forces()
+	w.Time = time
+	for i := range w.Nodes {
+		w.Nodes[i].accelerate(half)
+	}
+}
+
+type RK4 struct {
+	x, y, vx, vy             []float64
+	sumX, sumY, sumVX, sumVY []float64
+}
+
+func grow(buf []float64, n int) []float64 {
+	if cap(buf) < n {
+		return make([]float64, n)
+	}
+	return buf[:n]
+}
+
+func (r *RK4) resize(n int) {
+	r.x = grow(r.x, n)
+	r.y = grow(r.y, n)
+	r.vx = grow(r.vx, n)
+	r.vy = grow(r.vy, n)
+	r.sumX = grow(r.sumX, n)
+	r.sumY = grow(r.sumY, n)
+	r.sumVX = grow(r.sumVX, n)
+	r.sumVY = grow(r.sumVY, n)
+}
+
+func (r *RK4) Integrate(w *World, duration float64) {
+	nodes := w.Nodes
+	r.resize(len(nodes))
+	for i := range nodes {
+		n := &nodes[i]
+		r.x[i], r.y[i] = n.X, n.Y
+		r.vx[i], r.vy[i] = n.VelocityX, n.VelocityY
+		r.sumX[i], r.sumY[i], r.sumVX[i], r.sumVY[i] = 0, 0, 0, 0
+	}
+	weights := [4]float64{1, 2, 2, 1}
+	offsets := [4]float64{.5, .5, 1, 0}
+	stages := [4]float64{0, .5, .5, 1}
+	time := w.Time
+	for k, weight := range weights {
+		w.Time = time + duration*stages[k]
+		w.Forces()
+		h := duration * offsets[k]
+		for i := range nodes {
+			n := &nodes[i]
+			aX, aY := n.forceX*n.inverseMass(), n.forceY*n.inverseMass()
+			r.sumX[i] += weight * n.VelocityX
+			r.sumY[i] += weight * n.VelocityY
+			r.sumVX[i] += weight * aX
+			r.sumVY[i] += weight * aY
+			n.X = r.x[i] + h*n.VelocityX
+			n.Y = r.y[i] + h*n.VelocityY
+			n.VelocityX = r.vx[i] + h*aX
+			n.VelocityY = r.vy[i] + h*aY
+		}
+	}
+	w.Time = time
+	h := duration / 6
+	for i := range nodes {
+		n := &nodes[i]
+		n.VelocityX = r.vx[i] + h*r.sumVX[i]
+		n.VelocityY = r.vy[i] + h*r.sumVY[i]
+		moveX, moveY := h*r.sumX[i], h*r.sumY[i]
+		if velocityCap := n.limitMove(distanceXY(moveX, moveY), w.VelocityCap); velocityCap < 1 {
+			moveX *= velocityCap
+			moveY *= velocityCap
+			n.VelocityX *= velocityCap
+			n.VelocityY *= velocityCap
+		}
+		n.X = r.x[i] + moveX
+		n.Y = r.y[i] + moveY
+	}
+}
@@ -0,0 +1,389 @@
+package springweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const FormatVersion = 1
+
+type FormatError struct {
+	Path, Message string
+}
+
+func (e *FormatError) Error() string {
+	if e.Path == "" {
+		return "springweb: " + e.Message
+	}
+	return "springweb: " + e.Path + ": " + e.Message
+}
+
+type boundsFile struct {
+	Left            float64 `json:"left"`
+	Top             float64 `json:"top"`
+	Right           float64 `json:"right"`
+	Bottom          float64 `json:"bottom"`
+	Bounce          float64 `json:"bounce"`
+	StaticFriction  float64 `json:"staticFriction,omitempty"`
+	KineticFriction float64 `json:"kineticFriction,omitempty"`
+}
+
+type materialFile struct {
+	Restitution     float64 `json:"restitution"`
+	StaticFriction  float64 `json:"staticFriction,omitempty"`
+	KineticFriction float64 `json:"kineticFriction,omitempty"`
+}
+
+type worldFile struct {
+	ArmResist    float64     `json:"armResist"`
+	SpringResist float64     `json:"springResist"`
+	DampingMode  DampingMode `json:"dampingMode,omitempty"`
+	GravityX     float64     `json:"gravityX,omitempty"`
+	GravityY     float64     `json:"gravityY,omitempty"`
+	MaxDuration  float64     `json:"maxDuration,omitempty"`
+	VelocityCap  *float64    `json:"velocityCap,omitempty"`
+	Bounds       *boundsFile `json:"bounds,omitempty"`
+}
+
+type nodeFile struct {
+	X        float64       `json:"x"`
+	Y        float64       `json:"y"`
+	R        float64       `json:"r"`
+	M        float64       `json:"m"`
+	Pinned   bool          `json:"pinned,omitempty"`
+	Material *materialFile `json:"material,omitempty"`
+}
+
+type armFile struct {
+	K         float64 `json:"k"`
+	InitAngle float64 `json:"initAngle"`
+	Damping   float64 `json:"damping,omitempty"`
+	Yield     float64 `json:"yield,omitempty"`
+	Hardening float64 `json:"hardening,omitempty"`
+}
+
+type springFile struct {
+	From           int     `json:"from"`
+	To             int     `json:"to"`
+	K              float64 `json:"k"`
+	Distance       float64 `json:"distance"`
+	FromArm        armFile `json:"fromArm"`
+	ToArm          armFile `json:"toArm"`
+	Damping        float64 `json:"damping,omitempty"`
+	Yield          float64 `json:"yield,omitempty"`
+	Hardening      float64 `json:"hardening,omitempty"`
+	MaxTension     float64 `json:"maxTension,omitempty"`
+	MaxCompression float64 `json:"maxCompression,omitempty"`
+	MaxTorque      float64 `json:"maxTorque,omitempty"`
+}
+
+type webFile struct {
+	Version int          `json:"version"`
+	World   worldFile    `json:"world"`
+	Nodes   []nodeFile   `json:"nodes"`
+	Springs []springFile `json:"springs"`
+}
+
+type springFileV0 struct {
+	From int     `json:"from"`
+	To   int     `json:"to"`
+	K    float64 `json:"k"`
+	A    float64 `json:"a"`
+}
+
+type webFileV0 struct {
+	World   worldFile      `json:"world"`
+	Nodes   []nodeFile     `json:"nodes"`
+	Springs []springFileV0 `json:"springs"`
+}
+
+func armToFile(arm *Arm) armFile {
+	return armFile{K: arm.K, InitAngle: arm.InitAngle,
+		Damping: arm.Damping, Yield: arm.Yield, Hardening: arm.Hardening}
+}
+
+func (f *armFile) arm() Arm {
+	return Arm{K: f.K, InitAngle: f.InitAngle,
+		Damping: f.Damping, Yield: f.Yield, Hardening: f.Hardening}
+}
+
+func Marshal(w *World) ([]byte, error) {
+	f := webFile{Version: FormatVersion,
+		World: worldFile{ArmResist: w.ArmResist, SpringResist: w.SpringResist,
+			DampingMode: w.DampingMode, GravityX: w.GravityX, GravityY: w.GravityY,
+			MaxDuration: w.MaxDuration, VelocityCap: &w.VelocityCap},
+		Nodes:   make([]nodeFile, len(w.Nodes)),
+		Springs: []springFile{}}
+	if b := w.Bounds; b != nil {
+		f.World.Bounds = &boundsFile{b.Left, b.Top, b.Right, b.Bottom, b.Bounce,
+			b.StaticFriction, b.KineticFriction}
+	}
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		f.Nodes[i] = nodeFile{X: n.X, Y: n.Y, R: n.R, M: n.M, Pinned: n.Pinned}
+		if m := n.Material; m != nil {
+			f.Nodes[i].Material = &materialFile{m.Restitution, m.StaticFriction, m.KineticFriction}
+		}
+		for _, s := range n.Springs {
+			f.Springs = append(f.Springs, springFile{From: i, To: s.To,
+				K: s.K, Distance: s.Distance,
+				FromArm: armToFile(&s.FromArm), ToArm: armToFile(&s.ToArm),
+				Damping: s.Damping, Yield: s.Yield, Hardening: s.Hardening,
+				MaxTension: s.MaxTension, MaxCompression: s.MaxCompression,
+				MaxTorque: s.MaxTorque})
+		}
+	}
+	return json.MarshalIndent(&f, "", "  ")
+}
+
+func Unmarshal(data []byte) (*World, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, decodeError(err)
+	}
+	var f webFile
+	switch {
+	case header.Version == 0:
+		var old webFileV0
+		if err := json.Unmarshal(data, &old); err != nil {
+			return nil, decodeError(err)
+		}
+		if err := old.validate(); err != nil {
+			return nil, err
+		}
+		f = old.migrate()
+	case header.Version == FormatVersion:
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, decodeError(err)
+		}
+	default:
+		return nil, &FormatError{"version",
+			fmt.Sprintf("unsupported version %d", header.Version)}
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f.world(), nil
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		var path strings.Builder
+		for _, part := range strings.Split(typeErr.Field, ".") {
+			if _, err := strconv.Atoi(part); err == nil {
+				path.WriteString("[" + part + "]")
+				continue
+			}
+			if path.Len() != 0 {
+				path.WriteByte('.')
+			}
+			path.WriteString(part)
+		}
+		return &FormatError{path.String(), fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type)}
+	case errors.As(err, &syntaxErr):
+		return &FormatError{fmt.Sprintf("offset %d", syntaxErr.Offset), syntaxErr.Error()}
+	}
+	return &FormatError{"", err.Error()}
+}
+
+func (old *webFileV0) validate() error {
+	for i, s := range old.Springs {
+		path := fmt.Sprintf("springs[%d]", i)
+		if err := validateEnds(path, s.From, s.To, len(old.Nodes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (old *webFileV0) migrate() webFile {
+	f := webFile{Version: FormatVersion, World: old.World, Nodes: old.Nodes,
+		Springs: make([]springFile, len(old.Springs))}
+	for i, s := range old.Springs {
+		a, b := &old.Nodes[s.From], &old.Nodes[s.To]
+		xDiff, yDiff := b.X-a.X, b.Y-a.Y
+		angle := math.Atan2(yDiff, xDiff)
+		f.Springs[i] = springFile{From: s.From, To: s.To, K: s.K,
+			Distance: distanceXY(xDiff, yDiff),
+			FromArm:  armFile{K: s.A, InitAngle: angle},
+			ToArm:    armFile{K: s.A, InitAngle: math.Atan2(-yDiff, -xDiff)}}
+	}
+	return f
+}
+
+func validateEnds(path string, from, to, n int) error {
+	if from < 0 || from >= n {
+		return &FormatError{path + ".from", fmt.Sprintf("node %d out of range", from)}
+	}
+	if to < 0 || to >= n {
+		return &FormatError{path + ".to", fmt.Sprintf("node %d out of range", to)}
+	}
+	if from == to {
+		return &FormatError{path + ".to", "spring connects a node to itself"}
+	}
+	return nil
+}
+
+func validateFinite(path string, values ...float64) error {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return &FormatError{path, "not a finite number"}
+		}
+	}
+	return nil
+}
+
+func validateMaterial(path string, restitution, staticFriction, kineticFriction float64) error {
+	if err := validateFinite(path, restitution, staticFriction, kineticFriction); err != nil {
+		return err
+	}
+	if restitution < 0 || staticFriction < 0 || kineticFriction < 0 {
+		return &FormatError{path, "negative coefficient"}
+	}
+	return nil
+}
+
+type field struct {
+	name  string
+	value float64
+}
+
+func validateNonNegative(path string, fields ...field) error {
+	for _, f := range fields {
+		if err := validateFinite(path+"."+f.name, f.value); err != nil {
+			return err
+		}
+		if f.value < 0 {
+			return &FormatError{path + "." + f.name, "negative"}
+		}
+	}
+	return nil
+}
+
+func (f *armFile) validate(path string) error {
+	return validateNonNegative(path, field{"k", f.K}, field{"damping", f.Damping},
+		field{"yield", f.Yield}, field{"hardening", f.Hardening})
+}
+
+func (f *webFile) validate() error {
+	if f.World.DampingMode != CoulombDamping && f.World.DampingMode != ViscousDamping {
+		return &FormatError{"world.dampingMode",
+			fmt.Sprintf("unknown mode %d", f.World.DampingMode)}
+	}
+	if f.World.MaxDuration < 0 {
+		return &FormatError{"world.maxDuration", "negative"}
+	}
+	if c := f.World.VelocityCap; c != nil {
+		if err := validateNonNegative("world", field{"velocityCap", *c}); err != nil {
+			return err
+		}
+	}
+	if err := validateNonNegative("world", field{"armResist", f.World.ArmResist},
+		field{"springResist", f.World.SpringResist}); err != nil {
+		return err
+	}
+	if err := validateFinite("world.gravityX", f.World.GravityX); err != nil {
+		return err
+	}
+	if err := validateFinite("world.gravityY", f.World.GravityY); err != nil {
+		return err
+	}
+	if b := f.World.Bounds; b != nil {
+		if err := validateMaterial("world.bounds", b.Bounce, b.StaticFriction, b.KineticFriction); err != nil {
+			return err
+		}
+	}
+	for i, n := range f.Nodes {
+		path := fmt.Sprintf("nodes[%d]", i)
+		if err := validateFinite(path, n.X, n.Y, n.R, n.M); err != nil {
+			return err
+		}
+		if n.M <= 0 {
+			return &FormatError{path + ".m", "mass must be positive"}
+		}
+		if n.R <= 0 {
+			return &FormatError{path + ".r", "radius must be positive"}
+		}
+		if m := n.Material; m != nil {
+			if err := validateMaterial(path+".material", m.Restitution, m.StaticFriction, m.KineticFriction); err != nil {
+				return err
+			}
+		}
+	}
+	for i, s := range f.Springs {
+		path := fmt.Sprintf("springs[%d]", i)
+		if err := validateEnds(path, s.From, s.To, len(f.Nodes)); err != nil {
+			return err
+		}
+		if a, b := f.Nodes[s.From], f.Nodes[s.To]; a.X == b.X && a.Y == b.Y {
+			return &FormatError{path, "spring ends at the same position"}
+		}
+		if err := validateFinite(path, s.K, s.Distance,
+			s.FromArm.K, s.FromArm.InitAngle, s.ToArm.K, s.ToArm.InitAngle); err != nil {
+			return err
+		}
+		if s.K < 0 {
+			return &FormatError{path + ".k", "negative"}
+		}
+		if s.Distance < 0 {
+			return &FormatError{path + ".distance", "negative"}
+		}
+		if err := validateNonNegative(path, field{"damping", s.Damping},
+			field{"yield", s.Yield}, field{"hardening", s.Hardening},
+			field{"maxTension", s.MaxTension}, field{"maxCompression", s.MaxCompression},
+			field{"maxTorque", s.MaxTorque}); err != nil {
+			return err
+		}
+		if err := s.FromArm.validate(path + ".fromArm"); err != nil {
+			return err
+		}
+		if err := s.ToArm.validate(path + ".toArm"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *webFile) world() *World {
+	w := NewWorld(make([]Node, len(f.Nodes)))
+	w.ArmResist = f.World.ArmResist
+	w.SpringResist = f.World.SpringResist
+	w.DampingMode = f.World.DampingMode
+	w.GravityX = f.World.GravityX
+	w.GravityY = f.World.GravityY
+	w.MaxDuration = f.World.MaxDuration
+	if c := f.World.VelocityCap; c != nil {
+		w.VelocityCap = *c
+	}
+	if b := f.World.Bounds; b != nil {
+		w.Bounds = &Bounds{b.Left, b.Top, b.Right, b.Bottom, b.Bounce,
+			b.StaticFriction, b.KineticFriction}
+	}
+	for i, n := range f.Nodes {
+		w.Nodes[i] = NewNode(n.X, n.Y, n.R, n.M)
+		w.Nodes[i].Pinned = n.Pinned
+		if m := n.Material; m != nil {
+			w.Nodes[i].Material = &Material{m.Restitution, m.StaticFriction, m.KineticFriction}
+		}
+	}
+	for _, s := range f.Springs {
+		node := &w.Nodes[s.From]
+		d := distance(node, &w.Nodes[s.To])
+		node.Springs = append(node.Springs, Spring{To: s.To, K: s.K,
+			Distance: s.Distance, prevDistance: d, lastDistance: d,
+			FromArm: s.FromArm.arm(), ToArm: s.ToArm.arm(),
+			Damping: s.Damping, Yield: s.Yield, Hardening: s.Hardening,
+			MaxTension: s.MaxTension, MaxCompression: s.MaxCompression,
+			MaxTorque: s.MaxTorque})
+	}
+	return w
+}
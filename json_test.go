@@ -0,0 +1,85 @@
+package springweb
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	w := randomWeb(30, 2)
+	w.DampingMode = ViscousDamping
+	w.MaxDuration = .02
+	w.VelocityCap = 0
+	w.Bounds = &Bounds{Left: -5, Top: -5, Right: 85, Bottom: 40, Bounce: .5, StaticFriction: .3, KineticFriction: .2}
+	w.Nodes[4].Pinned = true
+	w.Nodes[2].Material = &Material{Restitution: .8, StaticFriction: .5, KineticFriction: .4}
+	s := &w.Nodes[1].Springs[0]
+	s.Damping, s.Yield, s.Hardening, s.MaxTension = .1, .2, .3, 40
+	s.FromArm.Damping, s.ToArm.Yield = .5, .6
+	data, err := Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Fatalf("round trip changed the document:\n%s\n%s", data, again)
+	}
+	c.Prepare()
+	if c.VelocityCap != 0 || *c.Bounds != *w.Bounds || *c.Nodes[2].Material != *w.Nodes[2].Material || c.Nodes[1].Springs[0] != *s {
+		t.Fatal("round trip changed the world")
+	}
+}
+
+func TestUnmarshalMigrates(t *testing.T) {
+	w, err := Unmarshal([]byte(`{"world": {"armResist": 0, "springResist": 0},
+		"nodes": [{"x": 0, "y": 0, "r": 1, "m": 1}, {"x": 3, "y": 4, "r": 1, "m": 1}],
+		"springs": [{"from": 0, "to": 1, "k": 2, "a": 7}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := w.Nodes[0].Springs[0]
+	if s.To != 1 || s.K != 2 || s.Distance != 5 || s.FromArm.K != 7 || s.ToArm.K != 7 {
+		t.Fatalf("migrated spring %+v", s)
+	}
+	if s.FromArm.InitAngle != math.Atan2(4, 3) || s.ToArm.InitAngle != math.Atan2(-4, -3) {
+		t.Fatalf("migrated angles %g, %g", s.FromArm.InitAngle, s.ToArm.InitAngle)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	const nodes = `"nodes": [{"x": 0, "y": 0, "r": 1, "m": 1}, {"x": 3, "y": 4, "r": 1, "m": 1}]`
+	for _, c := range []struct{ doc, path string }{
+		{`{"version": 1, "nodes": [{"x": 0}, {"x": "a"}]}`, "nodes[1].x"},
+		{`{"version": 1, "nodes": [}`, "offset 26"},
+		{`{"version": 9}`, "version"},
+		{`{"version": 1, "world": {"dampingMode": 2}}`, "world.dampingMode"},
+		{`{"version": 1, "world": {"armResist": -1}}`, "world.armResist"},
+		{`{"version": 1, "world": {"velocityCap": -1}}`, "world.velocityCap"},
+		{`{"version": 1, "nodes": [{"x": 0, "y": 0, "r": 1, "m": 0}]}`, "nodes[0].m"},
+		{`{"version": 1, ` + nodes + `, "springs": [{"from": 0, "to": 2, "k": 1}]}`, "springs[0].to"},
+		{`{"version": 1, ` + nodes + `, "springs": [{"from": 0, "to": 1, "k": 1, "fromArm": {"k": -1}}]}`, "springs[0].fromArm.k"},
+		{`{"version": 1, ` + nodes + `, "springs": [{"from": 0, "to": 1, "k": 1, "maxTorque": -1}]}`, "springs[0].maxTorque"},
+		{`{"version": 1, "nodes": [{"x": 1, "y": 1, "r": 1, "m": 1}, {"x": 1, "y": 1, "r": 1, "m": 1}],
+			"springs": [{"from": 0, "to": 1, "k": 1}]}`, "springs[0]"},
+		{`{"nodes": [{"x": 1, "y": 1, "r": 1, "m": 1}, {"x": 1, "y": 1, "r": 1, "m": 1}],
+			"springs": [{"from": 1, "to": 0, "k": 1, "a": 1}]}`, "springs[0]"},
+		{`{` + nodes + `, "springs": [{"from": -1, "to": 0, "k": 1, "a": 1}]}`, "springs[0].from"},
+	} {
+		_, err := Unmarshal([]byte(c.doc))
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) {
+			t.Errorf("%s: got %v, want a format error", c.doc, err)
+		} else if formatErr.Path != c.path {
+			t.Errorf("%s: path %q, want %q (%v)", c.doc, formatErr.Path, c.path, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package springweb
+
+import "math"
+
+type Material struct {
+	Restitution     float64
+	StaticFriction  float64
+	KineticFriction float64
+}
+
+func mix(a, b *Material, fallback Material) Material {
+	switch {
+	case a == nil && b == nil:
+		return fallback
+	case a == nil:
+		return *b
+	case b == nil:
+		return *a
+	}
+	return Material{Restitution: math.Max(a.Restitution, b.Restitution),
+		StaticFriction:  math.Sqrt(a.StaticFriction * b.StaticFriction),
+		KineticFriction: math.Sqrt(a.KineticFriction * b.KineticFriction)}
+}
+
+func (m *Material) friction(slip, impulse float64) float64 {
+	if slip <= m.StaticFriction*impulse {
+		return slip
+	}
+	return math.Min(slip, m.KineticFriction*impulse)
+}
+
+func (m *Material) reflect(normal, tangent *float64) {
+	impulse := (1 + m.Restitution) * math.Abs(*normal)
+	*normal *= -m.Restitution
+	if slip := math.Abs(*tangent); slip > 0 {
+		*tangent -= math.Copysign(m.friction(slip, impulse), *tangent)
+	}
+}
+
+func (node *Node) Collide(normalX, normalY float64, collider *Material) {
+	if node.fixed() {
+		return
+	}
+	m := mix(node.Material, collider, Material{})
+	approach := node.VelocityX*normalX + node.VelocityY*normalY
+	if approach >= 0 {
+		return
+	}
+	impulse := -(1 + m.Restitution) * approach
+	node.VelocityX += impulse * normalX
+	node.VelocityY += impulse * normalY
+	normal := node.VelocityX*normalX + node.VelocityY*normalY
+	slipX := node.VelocityX - normal*normalX
+	slipY := node.VelocityY - normal*normalY
+	if slip := distanceXY(slipX, slipY); slip > 0 {
+		f := m.friction(slip, impulse) / slip
+		node.VelocityX -= slipX * f
+		node.VelocityY -= slipY * f
+	}
+}
+
+func (b *Bounds) contact(m *Material) Material {
+	wall := Material{b.Bounce, b.StaticFriction, b.KineticFriction}
+	return mix(m, &wall, wall)
+}
@@ -0,0 +1,180 @@
+package springweb
+
+import (
+	"math"
+	"sort"
+)
+
+type Mode struct {
+	Frequency float64
+	X, Y      []float64
+}
+
+type freedom struct {
+	node int
+	y    bool
+}
+
+func (w *World) staticForces() {
+	nodes := w.Nodes
+	for i := range nodes {
+		n := &nodes[i]
+		n.avgRotationsPrepare()
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			s.weigh(n, &nodes[s.To])
+		}
+	}
+	avgRotations(nodes)
+	w.Forces()
+}
+
+func (w *World) coordinate(f freedom) *float64 {
+	if f.y {
+		return &w.Nodes[f.node].Y
+	}
+	return &w.Nodes[f.node].X
+}
+
+func (w *World) force(f freedom) float64 {
+	if f.y {
+		return w.Nodes[f.node].forceY
+	}
+	return w.Nodes[f.node].forceX
+}
+
+func (w *World) Modes(count int) []Mode {
+	c := &World{Web: *w.Web.Clone()}
+	var dofs []freedom
+	scale := 0.
+	springs := 0
+	for i := range c.Nodes {
+		n := &c.Nodes[i]
+		if !n.fixed() {
+			dofs = append(dofs, freedom{i, false}, freedom{i, true})
+		}
+		for _, s := range n.Springs {
+			scale += s.Distance
+			springs++
+		}
+	}
+	if springs != 0 {
+		scale /= float64(springs)
+	}
+	h := 1e-6 * math.Max(scale, 1)
+	m := len(dofs)
+	a := make([][]float64, m)
+	for i := range a {
+		a[i] = make([]float64, m)
+	}
+	plus := make([]float64, m)
+	for b, fb := range dofs {
+		x := c.coordinate(fb)
+		x0 := *x
+		*x = x0 + h
+		c.staticForces()
+		for i, fa := range dofs {
+			plus[i] = c.force(fa)
+		}
+		*x = x0 - h
+		c.staticForces()
+		for i, fa := range dofs {
+			a[i][b] = -(plus[i] - c.force(fa)) / (2 * h)
+		}
+		*x = x0
+	}
+	for i := 0; i < m; i++ {
+		mi := c.Nodes[dofs[i].node].M
+		for j := 0; j < i; j++ {
+			mj := c.Nodes[dofs[j].node].M
+			v := .5 * (a[i][j] + a[j][i]) / math.Sqrt(mi*mj)
+			a[i][j], a[j][i] = v, v
+		}
+		a[i][i] /= mi
+	}
+	values, vectors := jacobi(a)
+	order := make([]int, m)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+	if count > m {
+		count = m
+	}
+	modes := make([]Mode, count)
+	for k := range modes {
+		e := order[k]
+		mode := Mode{Frequency: math.Sqrt(math.Max(values[e], 0)) / (2 * math.Pi),
+			X: make([]float64, len(c.Nodes)), Y: make([]float64, len(c.Nodes))}
+		peak := 0.
+		for i, f := range dofs {
+			v := vectors[i][e] / math.Sqrt(c.Nodes[f.node].M)
+			if f.y {
+				mode.Y[f.node] = v
+			} else {
+				mode.X[f.node] = v
+			}
+			peak = math.Max(peak, math.Abs(v))
+		}
+		if peak > 0 {
+			for i := range mode.X {
+				mode.X[i] /= peak
+				mode.Y[i] /= peak
+			}
+		}
+		modes[k] = mode
+	}
+	return modes
+}
+
+func jacobi(a [][]float64) (values []float64, vectors [][]float64) {
+	n := len(a)
+	vectors = make([][]float64, n)
+	for i := range vectors {
+		vectors[i] = make([]float64, n)
+		vectors[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off, diag := 0., 0.
+		for i := 0; i < n; i++ {
+			diag += a[i][i] * a[i][i]
+			for j := i + 1; j < n; j++ {
+				off += a[i][j] * a[i][j]
+			}
+		}
+		if off <= 1e-24*diag || off == 0 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				cos := 1 / math.Sqrt(t*t+1)
+				sin := t * cos
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = cos*akp - sin*akq
+					a[k][q] = sin*akp + cos*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = cos*apk - sin*aqk
+					a[q][k] = sin*apk + cos*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := vectors[k][p], vectors[k][q]
+					vectors[k][p] = cos*vkp - sin*vkq
+					vectors[k][q] = sin*vkp + cos*vkq
+				}
+			}
+		}
+	}
+	values = make([]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+	}
+	return
+}
@@ -0,0 +1,54 @@
+package springweb
+
+type Motion interface {
+	Position(time float64) (x, y float64)
+}
+
+type MotionFunc func(time float64) (x, y float64)
+
+func (f MotionFunc) Position(time float64) (x, y float64) {
+	return f(time)
+}
+
+type Keyframe struct {
+	Time, X, Y float64
+}
+
+type Track []Keyframe
+
+func (t Track) Position(time float64) (x, y float64) {
+	n := len(t)
+	if n == 0 {
+		return 0, 0
+	}
+	if time <= t[0].Time {
+		return t[0].X, t[0].Y
+	}
+	for i := 1; i < n; i++ {
+		if time < t[i].Time {
+			a, b := t[i-1], t[i]
+			f := (time - a.Time) / (b.Time - a.Time)
+			return a.X + (b.X-a.X)*f, a.Y + (b.Y-a.Y)*f
+		}
+	}
+	return t[n-1].X, t[n-1].Y
+}
+
+func (node *Node) fixed() bool {
+	return node.Pinned || node.Motion != nil
+}
+
+func (node *Node) inverseMass() float64 {
+	if node.fixed() {
+		return 0
+	}
+	return 1 / node.M
+}
+
+func (node *Node) follow(time, duration float64) {
+	x, y := node.Motion.Position(time)
+	node.VelocityX = (x - node.stepX) / duration
+	node.VelocityY = (y - node.stepY) / duration
+	node.X = x
+	node.Y = y
+}
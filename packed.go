@@ -0,0 +1,218 @@
+package springweb
+
+import (
+	"errors"
+	"math"
+)
+
+type PackedSpring struct {
+	From int
+	Spring
+}
+
+type Packed struct {
+	X, Y, VX, VY, M, R      []float64
+	Angle                   []float64
+	Pinned                  []bool
+	Material                []*Material
+	Springs                 []PackedSpring
+	ArmResist, SpringResist float64
+	GravityX, GravityY      float64
+	Bounds                  *Bounds
+	MaxDuration             float64
+	VelocityCap             float64
+	Time                    float64
+	stepX, stepY            []float64
+	forceX, forceY          []float64
+	wAvgSum                 []float64
+}
+
+func packable(w *World) error {
+	switch w.Integrator.(type) {
+	case nil, SymplecticEuler, *SymplecticEuler:
+	default:
+		return errors.New("springweb: packed layout supports only the default integrator")
+	}
+	switch {
+	case len(w.Fields) != 0:
+		return errors.New("springweb: packed layout does not support force fields")
+	case w.Collisions != nil:
+		return errors.New("springweb: packed layout does not support collisions")
+	case w.DampingMode != CoulombDamping:
+		return errors.New("springweb: packed layout does not support viscous damping")
+	}
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		if n.Motion != nil {
+			return errors.New("springweb: packed layout does not support kinematic nodes")
+		}
+		for _, s := range n.Springs {
+			if s.MaxTension != 0 || s.MaxCompression != 0 || s.MaxTorque != 0 {
+				return errors.New("springweb: packed layout does not support breaking springs")
+			}
+			if s.Yield != 0 || s.FromArm.Yield != 0 || s.ToArm.Yield != 0 {
+				return errors.New("springweb: packed layout does not support yield")
+			}
+		}
+	}
+	return nil
+}
+
+func NewPacked(w *World) (*Packed, error) {
+	if err := packable(w); err != nil {
+		return nil, err
+	}
+	n := len(w.Nodes)
+	p := &Packed{ArmResist: w.ArmResist, SpringResist: w.SpringResist,
+		GravityX: w.GravityX, GravityY: w.GravityY, Bounds: w.Bounds,
+		MaxDuration: w.MaxDuration, VelocityCap: w.VelocityCap, Time: w.Time}
+	p.resize(n)
+	for i := range w.Nodes {
+		node := &w.Nodes[i]
+		p.X[i], p.Y[i] = node.X, node.Y
+		p.VX[i], p.VY[i] = node.VelocityX, node.VelocityY
+		p.M[i], p.R[i] = node.M, node.R
+		p.Angle[i], p.wAvgSum[i] = node.Angle, node.wAvgSum
+		p.Pinned[i] = node.Pinned
+		p.Material[i] = node.Material
+		p.stepX[i], p.stepY[i] = node.stepX, node.stepY
+		p.forceX[i], p.forceY[i] = node.forceX, node.forceY
+		for _, s := range node.Springs {
+			p.Springs = append(p.Springs, PackedSpring{From: i, Spring: s})
+		}
+	}
+	return p, nil
+}
+
+func (p *Packed) resize(n int) {
+	for _, buf := range []*[]float64{&p.X, &p.Y, &p.VX, &p.VY, &p.M, &p.R,
+		&p.Angle, &p.stepX, &p.stepY, &p.forceX, &p.forceY, &p.wAvgSum} {
+		*buf = grow(*buf, n)
+	}
+	if cap(p.Pinned) < n {
+		p.Pinned = make([]bool, n)
+	}
+	p.Pinned = p.Pinned[:n]
+	if cap(p.Material) < n {
+		p.Material = make([]*Material, n)
+	}
+	p.Material = p.Material[:n]
+}
+
+func (p *Packed) Store(w *World) {
+	k := 0
+	for i := range w.Nodes {
+		node := &w.Nodes[i]
+		node.X, node.Y = p.X[i], p.Y[i]
+		node.VelocityX, node.VelocityY = p.VX[i], p.VY[i]
+		node.Angle, node.wAvgSum = p.Angle[i], p.wAvgSum[i]
+		node.stepX, node.stepY = p.stepX[i], p.stepY[i]
+		node.forceX, node.forceY = p.forceX[i], p.forceY[i]
+		for j := range node.Springs {
+			node.Springs[j] = p.Springs[k].Spring
+			k++
+		}
+	}
+	w.Time = p.Time
+}
+
+func (p *Packed) Interpolate(i int, alpha float64) (x, y float64) {
+	x = p.stepX[i] + (p.X[i]-p.stepX[i])*alpha
+	y = p.stepY[i] + (p.Y[i]-p.stepY[i])*alpha
+	return
+}
+
+func (p *Packed) distance(i, j int) float64 {
+	return distanceXY(p.X[i]-p.X[j], p.Y[i]-p.Y[j])
+}
+
+func (p *Packed) angle(i, j int) float64 {
+	return math.Atan2(p.Y[j]-p.Y[i], p.X[j]-p.X[i])
+}
+
+func (p *Packed) push(i int, forceX, forceY float64) {
+	p.forceX[i] += forceX
+	p.forceY[i] += forceY
+}
+
+func (p *Packed) Step(duration float64) {
+	if p.MaxDuration > 0 && duration > p.MaxDuration {
+		duration = p.MaxDuration
+	}
+	n := len(p.X)
+	for i := 0; i < n; i++ {
+		p.stepX[i] = p.X[i]
+		p.stepY[i] = p.Y[i]
+		if p.Pinned[i] {
+			p.VX[i] = 0
+			p.VY[i] = 0
+		}
+		p.forceX[i] = p.M[i] * p.GravityX
+		p.forceY[i] = p.M[i] * p.GravityY
+	}
+	for k := range p.Springs {
+		s := &p.Springs[k]
+		s.weighAt(p.distance(s.From, s.To))
+	}
+	for k := range p.Springs {
+		s := &p.Springs[k]
+		i, j := s.From, s.To
+		xDiff, yDiff := p.X[j]-p.X[i], p.Y[j]-p.Y[i]
+		forceX, forceY := s.contract(xDiff, yDiff, p.R[i], p.R[j], p.SpringResist)
+		p.push(i, forceX, forceY)
+		p.push(j, -forceX, -forceY)
+		forceX, forceY = s.FromArm.torqueAt(xDiff, yDiff, p.Angle[i], p.ArmResist)
+		p.push(i, -forceX, -forceY)
+		p.push(j, forceX, forceY)
+		forceX, forceY = s.ToArm.torqueAt(p.X[i]-p.X[j], p.Y[i]-p.Y[j], p.Angle[j], p.ArmResist)
+		p.push(j, -forceX, -forceY)
+		p.push(i, forceX, forceY)
+	}
+	for i := 0; i < n; i++ {
+		if !p.Pinned[i] {
+			w := duration * (1 / p.M[i])
+			p.VX[i] += p.forceX[i] * w
+			p.VY[i] += p.forceY[i] * w
+		}
+		dMove := duration * distanceXY(p.VX[i], p.VY[i])
+		if velocityCap := limitMove(dMove, p.R[i], p.VelocityCap); velocityCap < 1 {
+			p.VX[i] *= velocityCap
+			p.VY[i] *= velocityCap
+		}
+		p.X[i] += p.VX[i] * duration
+		p.Y[i] += p.VY[i] * duration
+	}
+	if p.Bounds != nil {
+		for i := range p.X {
+			if !p.Pinned[i] {
+				p.Bounds.bound(&p.X[i], &p.Y[i], &p.VX[i], &p.VY[i], p.R[i], p.Material[i])
+			}
+		}
+	}
+	p.settle()
+	p.Time += duration
+}
+
+func (p *Packed) settle() {
+	for i := range p.Angle {
+		p.Angle[i] = 0
+		p.wAvgSum[i] = 0
+	}
+	for k := range p.Springs {
+		s := &p.Springs[k]
+		i, j := s.From, s.To
+		s.FromArm.rotate(p.angle(i, j), &p.Angle[i], &p.wAvgSum[i])
+		s.ToArm.rotate(p.angle(j, i), &p.Angle[j], &p.wAvgSum[j])
+	}
+	for i := range p.Angle {
+		if p.wAvgSum[i] != 0 {
+			p.Angle[i] /= p.wAvgSum[i]
+		}
+	}
+	for k := range p.Springs {
+		s := &p.Springs[k]
+		i, j := s.From, s.To
+		s.settleAt(p.distance(i, j), s.FromArm.unrestAt(p.angle(i, j), p.Angle[i]),
+			s.ToArm.unrestAt(p.angle(j, i), p.Angle[j]))
+	}
+}
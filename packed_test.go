@@ -0,0 +1,59 @@
+package springweb
+
+import "testing"
+
+func TestPackedMatchesWorld(t *testing.T) {
+	w := randomWeb(500, 7)
+	w.Bounds = &Bounds{Left: -5, Top: -5, Right: 85, Bottom: 110, Bounce: .5}
+	w.Nodes[3].Pinned = true
+	p, err := NewPacked(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for step := 0; step < 300; step++ {
+		w.Step(.005)
+		p.Step(.005)
+	}
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		if n.X != p.X[i] || n.Y != p.Y[i] || n.VelocityX != p.VX[i] || n.VelocityY != p.VY[i] || n.Angle != p.Angle[i] {
+			t.Fatalf("node %d: packed differs from world", i)
+		}
+	}
+	if allocs := testing.AllocsPerRun(10, func() { p.Step(.005) }); allocs != 0 {
+		t.Fatalf("packed step allocates %g times", allocs)
+	}
+	c := randomWeb(500, 7)
+	p.Store(c)
+	if c.Nodes[7].X != p.X[7] || c.Time != p.Time {
+		t.Fatal("store did not write back the packed state")
+	}
+}
+
+func TestPackedRejectsUnsupported(t *testing.T) {
+	w := randomWeb(10, 7)
+	w.Nodes[2].Motion = Track{{Time: 0, X: 0, Y: 0}}
+	if _, err := NewPacked(w); err == nil {
+		t.Fatal("kinematic node accepted")
+	}
+	w = randomWeb(10, 7)
+	w.Collisions = &Collisions{}
+	if _, err := NewPacked(w); err == nil {
+		t.Fatal("collisions accepted")
+	}
+}
+
+func benchmarkPacked(b *testing.B, n int) {
+	p, err := NewPacked(randomWeb(n, 1))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Step(.001)
+	}
+}
+
+func BenchmarkPacked4k(b *testing.B)  { benchmarkPacked(b, 4000) }
+func BenchmarkPacked16k(b *testing.B) { benchmarkPacked(b, 16000) }
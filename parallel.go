@@ -0,0 +1,149 @@
+package springweb
+
+import "sync"
+
+type springForce struct {
+	bounceX, bounceY     float64
+	fromX, fromY         float64
+	toX, toY             float64
+	dampX, dampY         float64
+	dampFromX, dampFromY float64
+	dampToX, dampToY     float64
+}
+
+type parallel struct {
+	owner, slot     []int
+	forces          []springForce
+	start, incident []int
+	fill            []int
+}
+
+func growInts(buf []int, n int) []int {
+	if cap(buf) < n {
+		return make([]int, n)
+	}
+	return buf[:n]
+}
+
+func split(workers, n int, f func(lo, hi int)) {
+	size := (n + workers - 1) / workers
+	if size == 0 {
+		return
+	}
+	var wg sync.WaitGroup
+	for lo := 0; lo < n; lo += size {
+		hi := lo + size
+		if hi > n {
+			hi = n
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			f(lo, hi)
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+func (p *parallel) index(nodes []Node) {
+	count := 0
+	for i := range nodes {
+		count += len(nodes[i].Springs)
+	}
+	p.owner = growInts(p.owner, count)
+	p.slot = growInts(p.slot, count)
+	if cap(p.forces) < count {
+		p.forces = make([]springForce, count)
+	}
+	p.forces = p.forces[:count]
+	p.start = growInts(p.start, len(nodes)+1)
+	p.fill = growInts(p.fill, len(nodes))
+	p.incident = growInts(p.incident, 2*count)
+	for k := range p.start {
+		p.start[k] = 0
+	}
+	f := 0
+	for i := range nodes {
+		for j, s := range nodes[i].Springs {
+			p.owner[f], p.slot[f] = i, j
+			p.start[i+1]++
+			p.start[s.To+1]++
+			f++
+		}
+	}
+	for k := 0; k < len(nodes); k++ {
+		p.start[k+1] += p.start[k]
+		p.fill[k] = p.start[k]
+	}
+	for f := 0; f < count; f++ {
+		i, to := p.owner[f], nodes[p.owner[f]].Springs[p.slot[f]].To
+		p.incident[p.fill[i]] = 2 * f
+		p.fill[i]++
+		p.incident[p.fill[to]] = 2*f + 1
+		p.fill[to]++
+	}
+}
+
+func (w *World) parallelForces() {
+	nodes := w.Nodes
+	p := &w.parallel
+	p.index(nodes)
+	viscous := w.DampingMode == ViscousDamping
+	springResist, armResist := w.SpringResist, w.ArmResist
+	if viscous {
+		springResist, armResist = 0, 0
+		armRates(nodes)
+	}
+	split(w.Workers, len(p.forces), func(lo, hi int) {
+		for f := lo; f < hi; f++ {
+			n := &nodes[p.owner[f]]
+			s := &n.Springs[p.slot[f]]
+			to := &nodes[s.To]
+			c := &p.forces[f]
+			c.bounceX, c.bounceY = s.contraction(n, to, springResist)
+			c.fromX, c.fromY = n.torqueForce(&s.FromArm, to, armResist)
+			c.toX, c.toY = to.torqueForce(&s.ToArm, n, armResist)
+			if viscous {
+				c.dampX, c.dampY = s.dampForce(n, to)
+				c.dampFromX, c.dampFromY = n.dampArmForce(&s.FromArm, to)
+				c.dampToX, c.dampToY = to.dampArmForce(&s.ToArm, n)
+			}
+		}
+	})
+	for k := range nodes {
+		n := &nodes[k]
+		n.forceX = n.M * w.GravityX
+		n.forceY = n.M * w.GravityY
+		for _, field := range w.Fields {
+			x, y := field.Force(n, w.Time)
+			n.push(x, y)
+		}
+	}
+	split(w.Workers, len(nodes), func(lo, hi int) {
+		for k := lo; k < hi; k++ {
+			n := &nodes[k]
+			for _, e := range p.incident[p.start[k]:p.start[k+1]] {
+				c := &p.forces[e>>1]
+				if e&1 == 0 {
+					n.push(c.bounceX, c.bounceY)
+					n.push(-c.fromX, -c.fromY)
+					n.push(c.toX, c.toY)
+					if viscous {
+						n.push(c.dampX, c.dampY)
+						n.push(-c.dampFromX, -c.dampFromY)
+						n.push(c.dampToX, c.dampToY)
+					}
+				} else {
+					n.push(-c.bounceX, -c.bounceY)
+					n.push(c.fromX, c.fromY)
+					n.push(-c.toX, -c.toY)
+					if viscous {
+						n.push(-c.dampX, -c.dampY)
+						n.push(c.dampFromX, c.dampFromY)
+						n.push(-c.dampToX, -c.dampToY)
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package springweb
+
+import "testing"
+
+func TestParallelForces(t *testing.T) {
+	for _, mode := range []DampingMode{CoulombDamping, ViscousDamping} {
+		serial, parallel := randomWeb(2000, 5), randomWeb(2000, 5)
+		for _, w := range []*World{serial, parallel} {
+			w.DampingMode = mode
+			w.Integrator = VelocityVerlet{}
+			w.Fields = []ForceField{Drag{Coefficient: .01}}
+			for i := range w.Nodes {
+				for j := range w.Nodes[i].Springs {
+					s := &w.Nodes[i].Springs[j]
+					s.Damping, s.FromArm.Damping = .1, .1
+				}
+			}
+		}
+		parallel.Workers = 4
+		for step := 0; step < 50; step++ {
+			serial.Step(.005)
+			parallel.Step(.005)
+		}
+		for i := range serial.Nodes {
+			a, b := &serial.Nodes[i], &parallel.Nodes[i]
+			if a.X != b.X || a.Y != b.Y || a.VelocityX != b.VelocityX || a.VelocityY != b.VelocityY {
+				t.Fatalf("mode %d node %d: parallel differs from serial", mode, i)
+			}
+		}
+	}
+}
+
+type countField struct {
+	calls int
+}
+
+func (f *countField) Force(n *Node, time float64) (x, y float64) {
+	f.calls++
+	return 0, 0
+}
+
+func TestParallelFieldsSerial(t *testing.T) {
+	w := randomWeb(2000, 5)
+	f := &countField{}
+	w.Fields = []ForceField{f}
+	w.Workers = 4
+	w.Step(.005)
+	if f.calls != len(w.Nodes) {
+		t.Fatalf("field called %d times for %d nodes", f.calls, len(w.Nodes))
+	}
+}
+
+func benchmarkStep(b *testing.B, n, workers int) {
+	w := randomWeb(n, 1)
+	w.Workers = workers
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Step(.001)
+	}
+}
+
+func BenchmarkSerial4k(b *testing.B)    { benchmarkStep(b, 4000, 1) }
+func BenchmarkSerial16k(b *testing.B)   { benchmarkStep(b, 16000, 1) }
+func BenchmarkParallel4k(b *testing.B)  { benchmarkStep(b, 4000, 4) }
+func BenchmarkParallel16k(b *testing.B) { benchmarkStep(b, 16000, 4) }
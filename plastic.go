@@ -0,0 +1,41 @@
+package springweb
+
+import "math"
+
+func flow(unrest, yield, hardening, plastic float64) float64 {
+	excess := math.Abs(unrest) - yield - hardening*plastic
+	if excess <= 0 {
+		return 0
+	}
+	return math.Copysign(excess/(1+hardening), unrest)
+}
+
+func (arm *Arm) yield(node, to *Node) {
+	if arm.Yield <= 0 {
+		return
+	}
+	f := flow(arm.unrest(node, to), arm.Yield, arm.Hardening, arm.plastic)
+	arm.InitAngle += f
+	arm.plastic += math.Abs(f)
+}
+
+func (s *Spring) yield(node, to *Node) {
+	if s.Yield > 0 && s.Distance > 0 {
+		strain := (distance(node, to) - s.Distance) / s.Distance
+		f := flow(strain, s.Yield, s.Hardening, s.plastic)
+		s.Distance *= 1 + f
+		s.plastic += math.Abs(f)
+	}
+	s.FromArm.yield(node, to)
+	s.ToArm.yield(to, node)
+}
+
+func (w *World) yield() {
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			s.yield(n, &w.Nodes[s.To])
+		}
+	}
+}
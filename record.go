@@ -0,0 +1,113 @@
+package springweb
+
+import "errors"
+
+type EventKind int
+
+const (
+	EventSelect EventKind = iota + 1
+	EventDrag
+	EventRelease
+	EventForce
+)
+
+type Event struct {
+	Kind  EventKind `json:"kind"`
+	Dot   int       `json:"dot,omitempty"`
+	X     float64   `json:"x,omitempty"`
+	Y     float64   `json:"y,omitempty"`
+	Value float64   `json:"value,omitempty"`
+}
+
+type Frame struct {
+	Elapsed float64 `json:"elapsed"`
+	Events  []Event `json:"events,omitempty"`
+}
+
+type Recording struct {
+	Program     string      `json:"program,omitempty"`
+	Seed        int64       `json:"seed"`
+	Duration    float64     `json:"duration"`
+	MaxSubsteps int         `json:"maxSubsteps"`
+	Collisions  *Collisions `json:"collisions,omitempty"`
+	VelocityCap *float64    `json:"velocityCap,omitempty"`
+	Snapshot    []byte      `json:"snapshot"`
+	Frames      []Frame     `json:"frames"`
+	pending     []Event
+}
+
+func NewRecording(r *Runner, seed int64) (*Recording, error) {
+	snapshot, err := r.World.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	rec := &Recording{Seed: seed, Duration: r.Duration, MaxSubsteps: r.MaxSubsteps,
+		Snapshot: snapshot}
+	velocityCap := r.World.VelocityCap
+	rec.VelocityCap = &velocityCap
+	if r.World.Collisions != nil {
+		rec.Collisions = r.World.Collisions.settings()
+	}
+	return rec, nil
+}
+
+func (rec *Recording) Event(e Event) {
+	rec.pending = append(rec.pending, e)
+}
+
+func (rec *Recording) Frame(elapsed float64) {
+	rec.Frames = append(rec.Frames, Frame{Elapsed: elapsed, Events: rec.pending})
+	rec.pending = nil
+}
+
+func (rec *Recording) Runner(w *World) (*Runner, error) {
+	if rec.Duration <= 0 {
+		return nil, errors.New("springweb: recording without step duration")
+	}
+	if err := w.UnmarshalBinary(rec.Snapshot); err != nil {
+		return nil, err
+	}
+	w.Collisions = rec.Collisions
+	if rec.VelocityCap != nil {
+		w.VelocityCap = *rec.VelocityCap
+	}
+	return NewRunner(w, rec.Duration, rec.MaxSubsteps), nil
+}
+
+type Grab struct {
+	Dot      int
+	X, Y     float64
+	Active   bool
+	duration float64
+}
+
+func (g *Grab) apply(w *World) {
+	if !g.Active || g.Dot < 0 || g.Dot >= len(w.Nodes) {
+		return
+	}
+	node := &w.Nodes[g.Dot]
+	if g.duration > 0 {
+		node.VelocityX = .1 * (9*node.VelocityX + (g.X-node.X)/g.duration)
+		node.VelocityY = .1 * (9*node.VelocityY + (g.Y-node.Y)/g.duration)
+	}
+	node.Place(g.X, g.Y)
+}
+
+func (g *Grab) Step(w *World, duration float64) {
+	g.duration = duration
+	g.apply(w)
+}
+
+func (g *Grab) Apply(w *World, e Event) {
+	switch e.Kind {
+	case EventSelect:
+		g.Dot = e.Dot
+	case EventDrag:
+		g.Dot = e.Dot
+		g.X, g.Y = e.X, e.Y
+		g.Active = true
+		g.apply(w)
+	case EventRelease:
+		g.Active = false
+	}
+}
@@ -0,0 +1,66 @@
+package springweb
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	w := randomWeb(40, 6)
+	w.Bounds = &Bounds{Left: -5, Top: -5, Right: 85, Bottom: 30, Bounce: .5, StaticFriction: .3, KineticFriction: .2}
+	w.Collisions = &Collisions{Restitution: .5, Continuous: true, Segments: true, SpringThickness: .2}
+	w.VelocityCap = 2
+	live := NewRunner(w, .01, 5)
+	rec, err := NewRecording(live, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var grab Grab
+	live.OnStep = func(duration float64) { grab.Step(w, duration) }
+	input := func(e Event) {
+		grab.Apply(w, e)
+		rec.Event(e)
+	}
+	for frame := 0; frame < 120; frame++ {
+		switch frame {
+		case 10:
+			input(Event{Kind: EventSelect, Dot: 7})
+		case 20, 30, 40:
+			input(Event{Kind: EventDrag, Dot: 7, X: float64(frame), Y: 10})
+		case 50:
+			input(Event{Kind: EventRelease})
+		}
+		elapsed := .013 + .004*float64(frame%3)
+		rec.Frame(elapsed)
+		live.Advance(elapsed)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Recording
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	r, err := loaded.Runner(NewWorld(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replay Grab
+	r.OnStep = func(duration float64) { replay.Step(r.World, duration) }
+	for _, f := range loaded.Frames {
+		for _, e := range f.Events {
+			replay.Apply(r.World, e)
+		}
+		r.Advance(f.Elapsed)
+	}
+	if r.World.Time != w.Time {
+		t.Fatalf("replay ended at %g, live at %g", r.World.Time, w.Time)
+	}
+	for i := range w.Nodes {
+		a, b := &w.Nodes[i], &r.World.Nodes[i]
+		if a.X != b.X || a.Y != b.Y || a.VelocityX != b.VelocityX || a.VelocityY != b.VelocityY {
+			t.Fatalf("node %d: replay differs from the live session", i)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package springweb
+
+import "math"
+
+type Runner struct {
+	World       *World
+	Duration    float64
+	MaxSubsteps int
+	OnStep      func(duration float64)
+	accumulated float64
+}
+
+func NewRunner(w *World, duration float64, maxSubsteps int) *Runner {
+	return &Runner{World: w, Duration: duration, MaxSubsteps: maxSubsteps}
+}
+
+func (r *Runner) Advance(elapsed float64) int {
+	if r.Duration <= 0 {
+		return 0
+	}
+	r.accumulated += elapsed
+	n := 0
+	for r.accumulated >= r.Duration {
+		if r.MaxSubsteps > 0 && n == r.MaxSubsteps {
+			r.accumulated = math.Mod(r.accumulated, r.Duration)
+			break
+		}
+		r.World.Step(r.Duration)
+		if r.OnStep != nil {
+			r.OnStep(r.Duration)
+		}
+		r.accumulated -= r.Duration
+		n++
+	}
+	return n
+}
+
+func (r *Runner) Alpha() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return r.accumulated / r.Duration
+}
+
+func (r *Runner) Reset() {
+	r.accumulated = 0
+}
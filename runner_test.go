@@ -0,0 +1,14 @@
+package springweb
+
+import "testing"
+
+func TestAdvanceWithoutDuration(t *testing.T) {
+	r := NewRunner(randomWeb(4, 1), 0, 0)
+	if n := r.Advance(.1); n != 0 || r.Alpha() != 0 {
+		t.Fatalf("advanced %d steps with alpha %g", n, r.Alpha())
+	}
+	r.Duration = .01
+	if n := r.Advance(.035); n != 3 {
+		t.Fatalf("advanced %d steps, want 3", n)
+	}
+}
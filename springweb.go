@@ -5,14 +5,24 @@ import "math"
 var ArmResist float64 = 1e-3
 var SpringResist float64 = 1e-3
 
+const VelocityCap = .6
+
 type Arm struct {
 	K, w, InitAngle, PrevAngle, prevAngleUnrest float64
+	lastAngleUnrest            float64
+	Yield, Hardening, plastic  float64
+	Damping                    float64
 	Rotations                  int
 }
 
 type Spring struct {
-	To             *Node
+	To             int
 	K,Distance,prevDistance    float64
+	lastDistance               float64
+	MaxTension, MaxCompression float64
+	MaxTorque                  float64
+	Yield, Hardening, plastic  float64
+	Damping                    float64
 	FromArm, ToArm Arm
 }
 
@@ -20,6 +30,13 @@ type Node struct {
 	X, Y, R, M             float64
 	VelocityX, VelocityY   float64
 	Angle, wAvgSum float64
+	spin, spinSum          float64
+	stepX, stepY           float64
+	forceX, forceY         float64
+	Pinned                 bool
+	capped                 bool
+	Material               *Material
+	Motion                 Motion
 	Springs                []Spring
 }
 
@@ -43,7 +60,7 @@ func (node *Node) Prepare() {
 }
 
 func distanceXY(xDiff, yDiff float64) float64 {
-	return math.Sqrt(math.Pow(xDiff, 2) + math.Pow(yDiff, 2))
+	return math.Sqrt(xDiff*xDiff + yDiff*yDiff)
 }
 
 func distance(a, b *Node) float64 {
@@ -55,48 +72,68 @@ func (node *Node) angle(to *Node) float64 {
 }
 
 func NewNode(x, y, r, m float64) Node {
-	return Node{x, y, r, m, 0, 0, 0, 0, nil}
+	return Node{X: x, Y: y, R: r, M: m, stepX: x, stepY: y}
+}
+
+func (node *Node) Place(x, y float64) {
+	node.X = x
+	node.Y = y
+	node.stepX = x
+	node.stepY = y
+}
+
+func (node *Node) Interpolate(alpha float64) (x, y float64) {
+	x = node.stepX + (node.X-node.stepX)*alpha
+	y = node.stepY + (node.Y-node.stepY)*alpha
+	return
+}
+
+func (node *Node) Force() (x, y float64) {
+	return node.forceX, node.forceY
+}
+
+func (node *Node) accelerate(duration float64) {
+	w := duration * node.inverseMass()
+	node.VelocityX += node.forceX * w
+	node.VelocityY += node.forceY * w
 }
 
-func (node *Node) NewSpring(to *Node, k, a float64) {
-	d := distance(node, to)
-	node.Springs = append(node.Springs,
-		Spring{to, k, d, d,
-			Arm{a, 0, node.angle(to), 0, 0, 0},
-			Arm{a, 0, to.angle(node), 0, 0, 0}})
+func (node *Node) push(forceX, forceY float64) {
+	node.forceX += forceX
+	node.forceY += forceY
 }
 
-func (node *Node) accelerate(forceX, forceY, duration float64) {
-	w := duration / node.M
-	node.VelocityX += forceX * w
-	node.VelocityY += forceY * w
+func (s *Spring) contraction(node, to *Node, resist float64) (forceX, forceY float64) {
+	return s.contract(to.X-node.X, to.Y-node.Y, node.R, to.R, resist)
 }
 
-func (s *Spring) bounce(node *Node, duration float64) {
-	xDiff := s.To.X - node.X
-	yDiff := s.To.Y - node.Y
+func (s *Spring) contract(xDiff, yDiff, r, toR, resist float64) (forceX, forceY float64) {
 	actualDistance := distanceXY(xDiff, yDiff)
 	xDiffN := xDiff / actualDistance
 	yDiffN := yDiff / actualDistance
 	contractF := s.K * (actualDistance - s.Distance)
 	distIncr := actualDistance - s.prevDistance
-	s.prevDistance = actualDistance
 	if distIncr > 0 {
-		contractF += SpringResist
+		contractF += resist
 	} else if distIncr < -0 {
-		contractF -= SpringResist
+		contractF -= resist
 	}
-	forceX := xDiffN * contractF
-	forceY := yDiffN * contractF
-	impactDepth := (node.R + s.To.R) - actualDistance
+	forceX = xDiffN * contractF
+	forceY = yDiffN * contractF
+	impactDepth := (r + toR) - actualDistance
 	if impactDepth > 0 {
-		refDepth := math.Min(node.R, s.To.R)
+		refDepth := math.Min(r, toR)
 		elasticF := s.K * s.Distance * impactDepth / refDepth
 		forceX -= xDiffN * elasticF
 		forceY -= yDiffN * elasticF
 	}
-	node.accelerate(forceX, forceY, duration)
-	s.To.accelerate(-forceX, -forceY, duration)
+	return
+}
+
+func (s *Spring) bounce(node, to *Node, resist float64) {
+	forceX, forceY := s.contraction(node, to, resist)
+	node.push(forceX, forceY)
+	to.push(-forceX, -forceY)
 }
 
 func (arm *Arm) updateAngle(angle float64) {
@@ -114,39 +151,131 @@ func (arm *Arm) Angle() float64 {
 	return arm.PrevAngle + float64(arm.Rotations)*math.Pi*2
 }
 
-func (node *Node) torque(arm *Arm, to *Node, duration float64) {
-	d := distance(node, to)
-	arm.w = arm.K / d
-	Angle := arm.Angle()
-	restAngle := arm.InitAngle + node.Angle
-	angleUnrest := Angle - restAngle
+func (arm *Arm) angleAt(angle float64) float64 {
+	diff := angle - arm.PrevAngle
+	rotations := arm.Rotations
+	if diff > math.Pi {
+		rotations--
+	}
+	if diff < -math.Pi {
+		rotations++
+	}
+	return angle + float64(rotations)*math.Pi*2
+}
+
+func (arm *Arm) unrest(node, to *Node) float64 {
+	return arm.unrestAt(node.angle(to), node.Angle)
+}
+
+func (arm *Arm) unrestAt(angle, nodeAngle float64) float64 {
+	restAngle := arm.InitAngle + nodeAngle
+	return arm.angleAt(angle) - restAngle
+}
+
+func (node *Node) torqueForce(arm *Arm, to *Node, resist float64) (forceX, forceY float64) {
+	return arm.torqueAt(to.X-node.X, to.Y-node.Y, node.Angle, resist)
+}
+
+func (arm *Arm) torqueAt(xDiff, yDiff, nodeAngle, resist float64) (forceX, forceY float64) {
+	d := distanceXY(xDiff, yDiff)
+	angleUnrest := arm.unrestAt(math.Atan2(yDiff, xDiff), nodeAngle)
 	unrestIncr := angleUnrest - arm.prevAngleUnrest
-	arm.prevAngleUnrest = angleUnrest
 	if unrestIncr > 0 {
-		angleUnrest += ArmResist*d
+		angleUnrest += resist * d
 	} else if unrestIncr < -0 {
-		angleUnrest -= ArmResist*d
+		angleUnrest -= resist * d
+	}
+	normalizeAndTorqueF := angleUnrest * arm.K / (d * d)
+	forceX = yDiff * normalizeAndTorqueF
+	forceY = -xDiff * normalizeAndTorqueF
+	return
+}
+
+func (node *Node) torque(arm *Arm, to *Node, resist float64) {
+	forceX, forceY := node.torqueForce(arm, to, resist)
+	node.push(-forceX, -forceY)
+	to.push(forceX, forceY)
+}
+
+func (s *Spring) torque(node, to *Node, resist float64) {
+	node.torque(&s.FromArm, to, resist)
+	to.torque(&s.ToArm, node, resist)
+}
+
+func (s *Spring) weigh(node, to *Node) {
+	s.weighAt(distance(node, to))
+}
+
+func (s *Spring) weighAt(d float64) {
+	s.FromArm.w = s.FromArm.K / d
+	s.ToArm.w = s.ToArm.K / d
+}
+
+func direction(incr float64) float64 {
+	if incr > 0 {
+		return 1
+	} else if incr < -0 {
+		return -1
 	}
-	normalizeAndTorqueF := angleUnrest * arm.w / d
-	forceX := (to.Y - node.Y) * normalizeAndTorqueF
-	forceY := (node.X - to.X) * normalizeAndTorqueF
-	node.accelerate(-forceX, -forceY, duration)
-	to.accelerate(forceX, forceY, duration)
+	return 0
+}
+
+func (arm *Arm) dissipation(node, to *Node, resist float64) float64 {
+	unrestIncr := arm.lastAngleUnrest - arm.prevAngleUnrest
+	angleIncr := arm.angleAt(node.angle(to)) - arm.Angle()
+	return resist * arm.K * distance(node, to) * direction(unrestIncr) * angleIncr
+}
+
+func (s *Spring) dissipation(node, to *Node, springResist, armResist float64) float64 {
+	distIncr := s.lastDistance - s.prevDistance
+	work := springResist * direction(distIncr) * (distance(node, to) - s.lastDistance)
+	work += s.FromArm.dissipation(node, to, armResist)
+	work += s.ToArm.dissipation(to, node, armResist)
+	return work
 }
 
-func (s *Spring) torque(node *Node, duration float64) {
-	node.torque(&s.FromArm, s.To, duration)
-	s.To.torque(&s.ToArm, node, duration)
+func (arm *Arm) settle(unrest float64) {
+	arm.prevAngleUnrest = arm.lastAngleUnrest
+	arm.lastAngleUnrest = unrest
 }
 
-func (node *Node) move(duration float64) {
+func (s *Spring) settle(node, to *Node) {
+	s.settleAt(distance(node, to), s.FromArm.unrest(node, to), s.ToArm.unrest(to, node))
+}
+
+func (s *Spring) settleAt(d, fromUnrest, toUnrest float64) {
+	s.prevDistance = s.lastDistance
+	s.lastDistance = d
+	s.FromArm.settle(fromUnrest)
+	s.ToArm.settle(toUnrest)
+}
+
+func (node *Node) limitMove(dMove, cap float64) float64 {
+	scale := limitMove(dMove, node.R, cap)
+	if scale < 1 {
+		node.capped = true
+	}
+	return scale
+}
+
+func limitMove(dMove, r, cap float64) float64 {
+	rMove := r * cap
+	if cap <= 0 || dMove <= rMove {
+		return 1
+	}
+	return rMove / dMove
+}
+
+func (node *Node) limit(duration, cap float64) {
 	dMove := duration * distanceXY(node.VelocityX, node.VelocityY)
-	rMove := node.R * .6
-	if dMove > rMove {
-		velocityCap := rMove / dMove
+	if velocityCap := node.limitMove(dMove, cap); velocityCap < 1 {
 		node.VelocityX *= velocityCap
 		node.VelocityY *= velocityCap
 	}
+}
+
+func (node *Node) move(duration, cap float64) {
+	node.limit(duration, cap)
 	node.X += node.VelocityX * duration
 	node.Y += node.VelocityY * duration
 }
@@ -156,44 +285,39 @@ func (node *Node) avgRotationsPrepare() {
 	node.wAvgSum = 0
 }
 
+func (arm *Arm) rotate(angle float64, nodeAngle, wAvgSum *float64) {
+	arm.updateAngle(angle)
+	*nodeAngle += (arm.Angle() - arm.InitAngle) * arm.w
+	*wAvgSum += arm.w
+}
+
+func (node *Node) rotate(arm *Arm, angle float64) {
+	arm.rotate(angle, &node.Angle, &node.wAvgSum)
+}
+
 func avgRotations(nodes []Node) {
-	iLast := len(nodes) - 1
-	for iForward, _ := range nodes {
-		i := iLast - iForward
+	for i := range nodes {
 		n := &nodes[i]
-		for j, _ := range n.Springs {
+		for j := range n.Springs {
 			s := &n.Springs[j]
-			t := s.To
-			s.FromArm.updateAngle(n.angle(t))
-			s.ToArm.updateAngle(t.angle(n))
-
-			n.Angle += (s.FromArm.Angle() - s.FromArm.InitAngle) * s.FromArm.w
-			t.Angle += (s.ToArm.Angle() - s.ToArm.InitAngle) * s.ToArm.w
-			n.wAvgSum += s.FromArm.w
-			t.wAvgSum += s.ToArm.w
+			t := &nodes[s.To]
+			n.rotate(&s.FromArm, n.angle(t))
+			t.rotate(&s.ToArm, t.angle(n))
+		}
+	}
+	for i := range nodes {
+		if n := &nodes[i]; n.wAvgSum != 0 {
+			n.Angle /= n.wAvgSum
 		}
-		n.Angle /= n.wAvgSum
 	}
 }
 
 func StepsPrepare(nodes []Node) {
-	for i, _ := range nodes {
-		nodes[i].Prepare()
-	}
+	w := NewWorld(nodes)
+	w.Prepare()
 }
 
 func Step(nodes []Node, duration float64) {
-	iLast := len(nodes) - 1
-	for iForward, _ := range nodes {
-		i := iLast - iForward
-		n := &nodes[i]
-		for j, _ := range n.Springs {
-			s := &n.Springs[j]
-			s.bounce(n, duration)
-			s.torque(n, duration)
-		}
-		n.move(duration)
-		n.avgRotationsPrepare()
-	}
-	avgRotations(nodes)
+	w := NewWorld(nodes)
+	w.Step(duration)
 }
@@ -0,0 +1,62 @@
+package springweb
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomWeb(n int, seed int64) *World {
+	r := rand.New(rand.NewSource(seed))
+	w := NewWorld(nil)
+	for i := 0; i < n; i++ {
+		w.AddNode(NewNode(float64(i%20)*4+r.Float64(), float64(i/20)*4+r.Float64(), 1, .5+r.Float64()))
+	}
+	for i := 0; i < n; i++ {
+		for _, k := range []int{1, 19, 20, 21} {
+			if j := i + k; j < n && r.Float64() < .8 {
+				if r.Intn(2) == 0 {
+					w.AddSpring(i, j, 1+r.Float64(), 20)
+				} else {
+					w.AddSpring(j, i, 1+r.Float64(), 20)
+				}
+			}
+		}
+	}
+	w.GravityY = 1
+	w.Prepare()
+	return w
+}
+
+func shuffled(w *World, perm []int) *World {
+	c := NewWorld(make([]Node, len(w.Nodes)))
+	c.GravityX, c.GravityY = w.GravityX, w.GravityY
+	for i := range w.Nodes {
+		n := w.Nodes[i]
+		n.Springs = append([]Spring(nil), n.Springs...)
+		for j := range n.Springs {
+			n.Springs[j].To = perm[n.Springs[j].To]
+		}
+		c.Nodes[perm[i]] = n
+	}
+	return c
+}
+
+func TestOrderIndependence(t *testing.T) {
+	w := randomWeb(60, 1)
+	w.AddSpring(0, 1, 2, 20)
+	w.AddSpring(1, 0, 2, 20)
+	w.Nodes[5].X += 2
+	perm := rand.New(rand.NewSource(2)).Perm(len(w.Nodes))
+	c := shuffled(w, perm)
+	for step := 0; step < 500; step++ {
+		w.Step(.01)
+		c.Step(.01)
+		for i := range w.Nodes {
+			a, b := &w.Nodes[i], &c.Nodes[perm[i]]
+			if !(math.Abs(a.X-b.X) <= 1e-9 && math.Abs(a.Y-b.Y) <= 1e-9) {
+				t.Fatalf("step %d node %d: (%g, %g) != (%g, %g)", step, i, a.X, a.Y, b.X, b.Y)
+			}
+		}
+	}
+}
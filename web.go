@@ -0,0 +1,70 @@
+package springweb
+
+type Web struct {
+	Nodes []Node
+}
+
+func (web *Web) AddNode(node Node) int {
+	web.Nodes = append(web.Nodes, node)
+	return len(web.Nodes) - 1
+}
+
+func (web *Web) RemoveNode(i int) {
+	for k := range web.Nodes {
+		n := &web.Nodes[k]
+		springs := n.Springs[:0]
+		for _, s := range n.Springs {
+			if s.To == i {
+				continue
+			}
+			if s.To > i {
+				s.To--
+			}
+			springs = append(springs, s)
+		}
+		n.Springs = springs
+	}
+	web.Nodes = append(web.Nodes[:i], web.Nodes[i+1:]...)
+}
+
+func (web *Web) AddSpring(i, j int, k, a float64) {
+	node, to := &web.Nodes[i], &web.Nodes[j]
+	d := distance(node, to)
+	node.Springs = append(node.Springs,
+		Spring{To: j, K: k, Distance: d, prevDistance: d, lastDistance: d,
+			FromArm: Arm{K: a, InitAngle: node.angle(to)},
+			ToArm:   Arm{K: a, InitAngle: to.angle(node)}})
+}
+
+func (web *Web) RemoveSpring(i, j int) bool {
+	return web.removeSpring(i, j) || web.removeSpring(j, i)
+}
+
+func (web *Web) removeSpring(i, j int) bool {
+	n := &web.Nodes[i]
+	for k := range n.Springs {
+		if n.Springs[k].To == j {
+			n.Springs = append(n.Springs[:k], n.Springs[k+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (web *Web) Clone() *Web {
+	clone := &Web{}
+	clone.Restore(web)
+	return clone
+}
+
+func (web *Web) Restore(snapshot *Web) {
+	web.Nodes = append(web.Nodes[:0], snapshot.Nodes...)
+	for i := range web.Nodes {
+		n := &web.Nodes[i]
+		n.Springs = append([]Spring(nil), n.Springs...)
+		if n.Material != nil {
+			m := *n.Material
+			n.Material = &m
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package springweb
+
+import "testing"
+
+func TestRemoveSpringEitherEnd(t *testing.T) {
+	var web Web
+	web.AddNode(NewNode(0, 0, 1, 1))
+	web.AddNode(NewNode(3, 0, 1, 1))
+	web.AddSpring(0, 1, 1, 1)
+	web.AddSpring(0, 1, 2, 1)
+	if !web.RemoveSpring(1, 0) || len(web.Nodes[0].Springs) != 1 {
+		t.Fatal("spring stored on the other end not removed")
+	}
+	if !web.RemoveSpring(0, 1) || web.RemoveSpring(1, 0) {
+		t.Fatal("removed a spring that is not there")
+	}
+}
@@ -0,0 +1,204 @@
+package springweb
+
+type Bounds struct {
+	Left, Top, Right, Bottom        float64
+	Bounce                          float64
+	StaticFriction, KineticFriction float64
+}
+
+type World struct {
+	Web
+	ArmResist, SpringResist float64
+	DampingMode             DampingMode
+	GravityX, GravityY      float64
+	Bounds                  *Bounds
+	MaxDuration             float64
+	VelocityCap             float64
+	Integrator              Integrator
+	Collisions              *Collisions
+	Fields                  []ForceField
+	Workers                 int
+	Time                    float64
+	OnBreak                 func(b Break)
+	Breaks                  []Break
+	OnCap                   func(node int)
+	Caps                    []int
+	dissipated              float64
+	parallel                parallel
+}
+
+func NewWorld(nodes []Node) *World {
+	return &World{Web: Web{Nodes: nodes}, ArmResist: ArmResist, SpringResist: SpringResist,
+		VelocityCap: VelocityCap}
+}
+
+func (w *World) Prepare() {
+	w.dissipated = 0
+	w.Time = 0
+	for i := range w.Nodes {
+		n := &w.Nodes[i]
+		n.Prepare()
+		if n.Motion != nil {
+			n.Place(n.Motion.Position(0))
+		}
+	}
+}
+
+func (w *World) Step(duration float64) {
+	w.StepWith(w.Integrator, duration)
+}
+
+func (w *World) StepWith(integrator Integrator, duration float64) {
+	if w.MaxDuration > 0 && duration > w.MaxDuration {
+		duration = w.MaxDuration
+	}
+	if integrator == nil {
+		integrator = SymplecticEuler{}
+	}
+	w.Caps = w.Caps[:0]
+	w.Breaks = w.Breaks[:0]
+	if s, ok := integrator.(stepper); ok {
+		s.step(w, duration)
+		return
+	}
+	w.begin()
+	integrator.Integrate(w, duration)
+	w.finish(duration)
+}
+
+func (w *World) begin() {
+	nodes := w.Nodes
+	for i := range nodes {
+		n := &nodes[i]
+		n.stepX = n.X
+		n.stepY = n.Y
+		if n.Pinned {
+			n.VelocityX = 0
+			n.VelocityY = 0
+		}
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			s.weigh(n, &nodes[s.To])
+		}
+	}
+}
+
+func (w *World) finish(duration float64) {
+	nodes := w.Nodes
+	for i := range nodes {
+		n := &nodes[i]
+		if n.capped {
+			n.capped = false
+			w.Caps = append(w.Caps, i)
+			if w.OnCap != nil {
+				w.OnCap(i)
+			}
+		}
+		if n.Motion != nil {
+			n.follow(w.Time+duration, duration)
+		}
+	}
+	if w.Collisions != nil {
+		w.Collisions.step(w, duration)
+	}
+	if w.Bounds != nil {
+		w.Bounds.step(nodes)
+	}
+	w.breakSprings(w.Time + duration)
+	w.yield()
+	w.settle(duration)
+	w.Time += duration
+}
+
+func (w *World) Forces() {
+	if w.Workers > 1 {
+		w.parallelForces()
+		return
+	}
+	nodes := w.Nodes
+	for i := range nodes {
+		n := &nodes[i]
+		n.forceX = n.M * w.GravityX
+		n.forceY = n.M * w.GravityY
+		for _, f := range w.Fields {
+			x, y := f.Force(n, w.Time)
+			n.push(x, y)
+		}
+	}
+	if w.DampingMode == ViscousDamping {
+		armRates(nodes)
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			to := &nodes[s.To]
+			if w.DampingMode == ViscousDamping {
+				s.bounce(n, to, 0)
+				s.torque(n, to, 0)
+				s.damp(n, to)
+			} else {
+				s.bounce(n, to, w.SpringResist)
+				s.torque(n, to, w.ArmResist)
+			}
+		}
+	}
+}
+
+func (w *World) settle(duration float64) {
+	nodes := w.Nodes
+	if w.DampingMode == ViscousDamping {
+		spin(nodes, func(arm *Arm, node, to *Node) float64 { return arm.turn(node, to, duration) })
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			to := &nodes[s.To]
+			if w.DampingMode == ViscousDamping {
+				w.dissipated += s.viscousDissipation(n, to, duration)
+			} else {
+				w.dissipated += s.dissipation(n, to, w.SpringResist, w.ArmResist)
+			}
+		}
+		n.avgRotationsPrepare()
+	}
+	avgRotations(nodes)
+	for i := range nodes {
+		n := &nodes[i]
+		for j := range n.Springs {
+			s := &n.Springs[j]
+			s.settle(n, &nodes[s.To])
+		}
+	}
+}
+
+func (b *Bounds) step(nodes []Node) {
+	for i := range nodes {
+		d := &nodes[i]
+		if d.fixed() {
+			continue
+		}
+		b.bound(&d.X, &d.Y, &d.VelocityX, &d.VelocityY, d.R, d.Material)
+	}
+}
+
+func (b *Bounds) bound(x, y, vx, vy *float64, r float64, material *Material) {
+	m := b.contact(material)
+	if *vx < 0 && *x < b.Left+r {
+		m.reflect(vx, vy)
+		*x = b.Left + r
+	}
+	if *vy < 0 && *y < b.Top+r {
+		m.reflect(vy, vx)
+		*y = b.Top + r
+	}
+	if *vx > 0 && *x > b.Right-r {
+		m.reflect(vx, vy)
+		*x = b.Right - r
+	}
+	if *vy > 0 && *y > b.Bottom-r {
+		m.reflect(vy, vx)
+		*y = b.Bottom - r
+	}
+}